@@ -4,10 +4,8 @@ package admin
 
 import (
 	"github.com/mongolar/mongolar/controller"
-	"github.com/mongolar/mongolar/services"
-	"github.com/mongolar/mongolar/user"
+	"github.com/mongolar/mongolar/middleware"
 	"github.com/mongolar/mongolar/wrapper"
-	"net/http"
 )
 
 // Admin Map for controllers
@@ -23,7 +21,7 @@ func (a *AdminMenu) AdminMenu(w *wrapper.Wrapper) {
 // Package function to return controller map
 func GetControllerMap(cm controller.ControllerMap) {
 	amap, _ := NewAdmin()
-	cm["admin"] = amap.Admin
+	cm["admin"] = middleware.NoCache(amap.Admin)
 }
 
 // A series of menu items to render on the admin page
@@ -41,63 +39,63 @@ func NewAdmin() (*AdminMap, *AdminMenu) {
 		},
 	}
 	amap := &AdminMap{
-		"admin_menu":         amenu.AdminMenu,
-		"paths":              AdminPaths,
-		"path_elements":      PathElements,
-		"path_editor":        PathEditor,
-		"element":            Element,
-		"element_editor":     ElementEditor,
-		"add_child":          AddChild,
-		"add_existing_child": AddExistingChild,
-		"all_content_types":  GetAllContentTypes,
-		"edit_content_type":  EditContentType,
-		"delete":             Delete,
-		"sort_children":      Sort,
-		"content":            ContentEditor,
-		"menu":               MenuEditor,
-		"content_type":       ContentTypeEditor,
-		"orphans":            OrphanElements,
-		"slug_url_editor":    SlugUrlEditor,
+		"admin_menu":           amenu.AdminMenu,
+		"paths":                AdminPaths,
+		"path_elements":        PathElements,
+		"path_editor":          PathEditor,
+		"element":              Element,
+		"element_editor":       ElementEditor,
+		"all_elements":         AllElements,
+		"add_child":            AddChild,
+		"add_existing_child":   AddExistingChild,
+		"all_content_types":    GetAllContentTypes,
+		"edit_content_type":    EditContentType,
+		"delete":               Delete,
+		"sort_children":        Sort,
+		"content":              ContentEditor,
+		"menu":                 MenuEditor,
+		"content_type":         ContentTypeEditor,
+		"orphans":              OrphanElements,
+		"slug_url_editor":      SlugUrlEditor,
+		"validate_content":     ValidateAllContent,
+		"export_content_types": ExportContentTypes,
+		"import_content_types": ImportContentTypes,
+		"export_site":          ExportSite,
+		"import_site":          ImportSite,
+		"integrity":            Integrity,
+		"trash":                Trash,
+		"restore":              RestoreTrash,
+		"purge":                PurgeTrash,
+		"clone_element":        CloneElement,
+		"duplicate_path":       DuplicatePath,
+		"move":                 MoveElement,
+		"where_used":           WhereUsed,
+		"media":                MediaLibrary,
+		"upload_media":         UploadMedia,
+		"delete_media":         DeleteMedia,
+		"image":                ImageEditor,
+		"markdown":             MarkdownEditor,
+		"list":                 ListEditor,
+		"redirects":            Redirects,
+		"redirect_editor":      RedirectEditor,
+		"delete_redirect":      DeleteRedirect,
+		"import_redirects":     ImportRedirects,
 	}
 	return amap, &amenu
 }
 
-//Main controller for all admin functions
+//Main controller for all admin functions, only admins can use them.
 func (a AdminMap) Admin(w *wrapper.Wrapper) {
-	w.Writer.Header().Add("Cache-Control", "no-cache, no-store, must-revalidate")
-	w.Writer.Header().Add("Pragma", "no-cache")
-	w.Writer.Header().Add("Expires", "0")
+	if len(w.APIParams) == 0 {
+		w.Error(403, "Forbidden")
+		return
+	}
 	if c, ok := a[w.APIParams[0]]; ok {
-		if validateAdmin(w) {
-			w.Shift()
-			c(w)
-		}
+		w.Shift()
+		middleware.RequireRole("admin")(c)(w)
 		return
 	} else {
-		http.Error(w.Writer, "Forbidden", 403)
+		w.Error(403, "Forbidden")
 		return
 	}
 }
-
-// Validate admin has admin role.
-func validateAdmin(w *wrapper.Wrapper) bool {
-	user := new(user.User)
-	err := user.Get(w)
-	loginurls := make(map[string]string)
-	w.SiteConfig.RawConfig.MarshalKey("LoginURLs", &loginurls)
-	if err != nil {
-		services.Redirect(loginurls["login"], w)
-		w.Serve()
-		return false
-	}
-	if user.Roles != nil {
-		for _, r := range user.Roles {
-			if r == "admin" {
-				return true
-			}
-		}
-	}
-	services.Redirect(loginurls["access_denied"], w)
-	w.Serve()
-	return false
-}
@@ -0,0 +1,67 @@
+package admin
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/mongolar/mongolar/bundle"
+	"github.com/mongolar/mongolar/services"
+	"github.com/mongolar/mongolar/wrapper"
+	"io/ioutil"
+)
+
+// Controller to download a bundle of the site.
+func ExportSite(w *wrapper.Wrapper) {
+	var b bytes.Buffer
+	_, err := bundle.Export(&b, w)
+	if err != nil {
+		errmessage := fmt.Sprintf("Unable to export site by %s: %s", w.Request.Host, err.Error())
+		w.Logger.Error(errmessage)
+		services.AddMessage("Unable to export the site.", "Error", w)
+		w.Serve()
+		return
+	}
+	w.ServeAttachment("site.zip", "application/zip", b.Bytes())
+	return
+}
+
+// Controller to import a posted site bundle.
+// The id option (preserve or remap) is the first parameter, the default is remap
+// so importing never overwrites existing documents.
+func ImportSite(w *wrapper.Wrapper) {
+	if w.Request.Method != "POST" {
+		w.Error(403, "Forbidden")
+		return
+	}
+	ids := bundle.IdsRemap
+	if len(w.APIParams) > 0 {
+		ids = w.APIParams[0]
+	}
+	b, err := ioutil.ReadAll(w.Request.Body)
+	if err != nil {
+		errmessage := fmt.Sprintf("Unable to read site import by %s: %s", w.Request.Host, err.Error())
+		w.Logger.Error(errmessage)
+		services.AddMessage("Unable to read your import.", "Error", w)
+		w.Serve()
+		return
+	}
+	r, err := bundle.Import(b, ids, w)
+	if err != nil {
+		errmessage := fmt.Sprintf("Unable to import site by %s: %s", w.Request.Host, err.Error())
+		w.Logger.Error(errmessage)
+		services.AddMessage("Unable to import the site.", "Error", w)
+		w.Serve()
+		return
+	}
+	for _, e := range r.Errors {
+		errmessage := fmt.Sprintf("Site import by %s: %s", w.Request.Host, e)
+		w.Logger.Error(errmessage)
+	}
+	if len(r.Errors) > 0 {
+		services.AddMessage("Some documents could not be imported.", "Error", w)
+	} else {
+		services.AddMessage("Site imported.", "Success", w)
+	}
+	w.SetPayload("import", r)
+	w.Serve()
+	return
+}
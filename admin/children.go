@@ -8,7 +8,6 @@ import (
 	"github.com/mongolar/mongolar/models/paths"
 	"github.com/mongolar/mongolar/services"
 	"github.com/mongolar/mongolar/wrapper"
-	"net/http"
 )
 
 // Sort controller to sort path and wrapper children
@@ -17,8 +16,7 @@ func Sort(w *wrapper.Wrapper) {
 	if len(w.APIParams) > 1 {
 		parenttype = w.APIParams[0]
 	} else {
-		http.Error(w.Writer, "Forbidden", 403)
-		w.Serve()
+		w.Error(403, "Forbidden")
 		return
 	}
 	w.Shift()
@@ -38,8 +36,7 @@ func Sort(w *wrapper.Wrapper) {
 		SortPathSubmit(w)
 		return
 	default:
-		http.Error(w.Writer, "Forbidden", 403)
-		w.Serve()
+		w.Error(403, "Forbidden")
 	}
 	return
 }
@@ -50,14 +47,13 @@ func SortWrapperForm(w *wrapper.Wrapper) {
 	if len(w.APIParams) > 0 {
 		parentid = w.APIParams[0]
 	} else {
-		http.Error(w.Writer, "Forbidden", 403)
-		w.Serve()
+		w.Error(403, "Forbidden")
 		return
 	}
 	e, err := elements.LoadWrapperElement(parentid, w)
 	if err != nil {
 		errmessage := fmt.Sprintf("Element not found to sort for %s by %s.", w.APIParams[1], w.Request.Host)
-		w.SiteConfig.Logger.Error(errmessage)
+		w.Logger.Error(errmessage)
 		services.AddMessage("This element was not found", "Error", w)
 		w.Serve()
 		return
@@ -79,14 +75,13 @@ func SortPathForm(w *wrapper.Wrapper) {
 	if len(w.APIParams) > 0 {
 		parentid = w.APIParams[0]
 	} else {
-		http.Error(w.Writer, "Forbidden", 403)
-		w.Serve()
+		w.Error(403, "Forbidden")
 		return
 	}
 	p, err := paths.LoadPath(parentid, w)
 	if err != nil {
 		errmessage := fmt.Sprintf("Path not found to sort for %s by %s.", parentid, w.Request.Host)
-		w.SiteConfig.Logger.Error(errmessage)
+		w.Logger.Error(errmessage)
 		services.AddMessage("This element was not found", "Error", w)
 		w.Serve()
 		return
@@ -108,15 +103,14 @@ func SortWrapperSubmit(w *wrapper.Wrapper) {
 	if len(w.APIParams) > 0 {
 		parentid = w.APIParams[0]
 	} else {
-		http.Error(w.Writer, "Forbidden", 403)
-		w.Serve()
+		w.Error(403, "Forbidden")
 		return
 	}
 	wes := elements.NewWrapperElements()
 	err := json.NewDecoder(w.Request.Body).Decode(&wes)
 	if err != nil {
 		errmessage := fmt.Sprintf("Unable to marshall elements %s by %s: %s", parentid, w.Request.Host, err.Error())
-		w.SiteConfig.Logger.Error(errmessage)
+		w.Logger.Error(errmessage)
 		services.AddMessage("Unable to save elements.", "Error", w)
 		w.Serve()
 		return
@@ -124,7 +118,7 @@ func SortWrapperSubmit(w *wrapper.Wrapper) {
 	we, err := elements.LoadWrapperElement(parentid, w)
 	if err != nil {
 		errmessage := fmt.Sprintf("Element not found to sort for %s by %s.", parentid, w.Request.Host)
-		w.SiteConfig.Logger.Error(errmessage)
+		w.Logger.Error(errmessage)
 		services.AddMessage("This element was not found", "Error", w)
 		w.Serve()
 		return
@@ -133,7 +127,7 @@ func SortWrapperSubmit(w *wrapper.Wrapper) {
 	we.Save(w)
 	if err != nil {
 		errmessage := fmt.Sprintf("Unable to save wrapper element %s by %s : %s", parentid, w.Request.Host, err.Error())
-		w.SiteConfig.Logger.Error(errmessage)
+		w.Logger.Error(errmessage)
 		services.AddMessage("Could not save parent element.", "Error", w)
 		w.Serve()
 		return
@@ -156,15 +150,14 @@ func SortPathSubmit(w *wrapper.Wrapper) {
 	if len(w.APIParams) > 0 {
 		parentid = w.APIParams[0]
 	} else {
-		http.Error(w.Writer, "Forbidden", 403)
-		w.Serve()
+		w.Error(403, "Forbidden")
 		return
 	}
 	pes := paths.NewPathElements()
 	err := json.NewDecoder(w.Request.Body).Decode(&pes)
 	if err != nil {
 		errmessage := fmt.Sprintf("Unable to marshall elements %s by %s: %s", parentid, w.Request.Host, err.Error())
-		w.SiteConfig.Logger.Error(errmessage)
+		w.Logger.Error(errmessage)
 		services.AddMessage("Unable to save elements.", "Error", w)
 		w.Serve()
 		return
@@ -172,7 +165,7 @@ func SortPathSubmit(w *wrapper.Wrapper) {
 	pe, err := paths.LoadPath(parentid, w)
 	if err != nil {
 		errmessage := fmt.Sprintf("Path not found to sort for %s by %s.", parentid, w.Request.Host)
-		w.SiteConfig.Logger.Error(errmessage)
+		w.Logger.Error(errmessage)
 		services.AddMessage("This path was not found", "Error", w)
 		w.Serve()
 		return
@@ -181,7 +174,7 @@ func SortPathSubmit(w *wrapper.Wrapper) {
 	err = pe.Save(w)
 	if err != nil {
 		errmessage := fmt.Sprintf("Unable to save path %s by %s : %s", parentid, w.Request.Host, err.Error())
-		w.SiteConfig.Logger.Error(errmessage)
+		w.Logger.Error(errmessage)
 		services.AddMessage("Could not save path.", "Error", w)
 		w.Serve()
 		return
@@ -204,8 +197,7 @@ func AddChild(w *wrapper.Wrapper) {
 	if len(w.APIParams) > 1 {
 		parenttype = w.APIParams[0]
 	} else {
-		http.Error(w.Writer, "Forbidden", 403)
-		w.Serve()
+		w.Error(403, "Forbidden")
 		return
 	}
 	w.Shift()
@@ -217,8 +209,7 @@ func AddChild(w *wrapper.Wrapper) {
 	case "paths":
 		AddPathChild(w)
 	default:
-		http.Error(w.Writer, "Forbidden", 403)
-		w.Serve()
+		w.Error(403, "Forbidden")
 	}
 	return
 }
@@ -231,7 +222,7 @@ func AddWrapperChild(w *wrapper.Wrapper) {
 	err := e.Save(w)
 	if err != nil {
 		errmessage := fmt.Sprintf("Unable to create new element  by %s : %s", w.Request.Host, err.Error())
-		w.SiteConfig.Logger.Error(errmessage)
+		w.Logger.Error(errmessage)
 		services.AddMessage("Could not create a new element.", "Error", w)
 		w.Serve()
 		return
@@ -240,7 +231,7 @@ func AddWrapperChild(w *wrapper.Wrapper) {
 	parent, err = elements.LoadWrapperElement(parentid, w)
 	if err != nil {
 		errmessage := fmt.Sprintf("Unable to loap parent element  by %s : %s", w.Request.Host, err.Error())
-		w.SiteConfig.Logger.Error(errmessage)
+		w.Logger.Error(errmessage)
 		services.AddMessage("Could not load parent element.", "Error", w)
 		w.Serve()
 		return
@@ -249,7 +240,7 @@ func AddWrapperChild(w *wrapper.Wrapper) {
 	err = parent.Save(w)
 	if err != nil {
 		errmessage := fmt.Sprintf("Unable to loap parent element  by %s : %s", w.Request.Host, err.Error())
-		w.SiteConfig.Logger.Error(errmessage)
+		w.Logger.Error(errmessage)
 		services.AddMessage("Could not load parent element.", "Error", w)
 		w.Serve()
 		return
@@ -275,7 +266,7 @@ func AddSlugChild(w *wrapper.Wrapper) {
 	err := e.Save(w)
 	if err != nil {
 		errmessage := fmt.Sprintf("Unable to create new element  by %s : %s", w.Request.Host, err.Error())
-		w.SiteConfig.Logger.Error(errmessage)
+		w.Logger.Error(errmessage)
 		services.AddMessage("Could not create a new element.", "Error", w)
 		w.Serve()
 		return
@@ -284,7 +275,7 @@ func AddSlugChild(w *wrapper.Wrapper) {
 	parent, err = elements.LoadSlugElement(parentid, w)
 	if err != nil {
 		errmessage := fmt.Sprintf("Unable to loap parent element  by %s : %s", w.Request.Host, err.Error())
-		w.SiteConfig.Logger.Error(errmessage)
+		w.Logger.Error(errmessage)
 		services.AddMessage("Could not load parent element.", "Error", w)
 		w.Serve()
 		return
@@ -298,7 +289,7 @@ func AddSlugChild(w *wrapper.Wrapper) {
 	err = parent.Save(w)
 	if err != nil {
 		errmessage := fmt.Sprintf("Unable to save parent element  by %s : %s", w.Request.Host, err.Error())
-		w.SiteConfig.Logger.Error(errmessage)
+		w.Logger.Error(errmessage)
 		services.AddMessage("Could not save parent element.", "Error", w)
 		w.Serve()
 		return
@@ -323,7 +314,7 @@ func AddPathChild(w *wrapper.Wrapper) {
 	err := e.Save(w)
 	if err != nil {
 		errmessage := fmt.Sprintf("Unable to create new element  by %s : %s", w.Request.Host, err.Error())
-		w.SiteConfig.Logger.Error(errmessage)
+		w.Logger.Error(errmessage)
 		services.AddMessage("Could not create a new element.", "Error", w)
 		w.Serve()
 		return
@@ -332,7 +323,7 @@ func AddPathChild(w *wrapper.Wrapper) {
 	parent, err = paths.LoadPath(parentid, w)
 	if err != nil {
 		errmessage := fmt.Sprintf("Unable to loap path  by %s : %s", w.Request.Host, err.Error())
-		w.SiteConfig.Logger.Error(errmessage)
+		w.Logger.Error(errmessage)
 		services.AddMessage("Could not load parent path.", "Error", w)
 		w.Serve()
 		return
@@ -341,7 +332,7 @@ func AddPathChild(w *wrapper.Wrapper) {
 	err = parent.Save(w)
 	if err != nil {
 		errmessage := fmt.Sprintf("Unable to save path by %s : %s", w.Request.Host, err.Error())
-		w.SiteConfig.Logger.Error(errmessage)
+		w.Logger.Error(errmessage)
 		services.AddMessage("Could not add child element.", "Error", w)
 		w.Serve()
 		return
@@ -364,8 +355,7 @@ func AddExistingChild(w *wrapper.Wrapper) {
 	if len(w.APIParams) > 1 {
 		parenttype = w.APIParams[0]
 	} else {
-		http.Error(w.Writer, "Forbidden", 403)
-		w.Serve()
+		w.Error(403, "Forbidden")
 		return
 	}
 	if w.Request.Method != "POST" {
@@ -381,8 +371,7 @@ func AddExistingChild(w *wrapper.Wrapper) {
 		AddExistingPathSubmit(w)
 		return
 	default:
-		http.Error(w.Writer, "Forbidden", 403)
-		w.Serve()
+		w.Error(403, "Forbidden")
 	}
 	return
 }
@@ -392,7 +381,7 @@ func AddExistingChildForm(w *wrapper.Wrapper) {
 	elems, err := elements.ElementList(w)
 	if err != nil {
 		errmessage := fmt.Sprintf("Unable to retrieve a list of all elements: %s", err.Error())
-		w.SiteConfig.Logger.Error(errmessage)
+		w.Logger.Error(errmessage)
 		services.AddMessage("There was a problem retrieving the element list.", "Error", w)
 		w.Serve()
 		return
@@ -424,7 +413,7 @@ func AddExistingWrapperSubmit(w *wrapper.Wrapper) {
 	parent, err = elements.LoadWrapperElement(parentid, w)
 	if err != nil {
 		errmessage := fmt.Sprintf("Unable to loap parent element  by %s : %s", w.Request.Host, err.Error())
-		w.SiteConfig.Logger.Error(errmessage)
+		w.Logger.Error(errmessage)
 		services.AddMessage("Could not load parent element.", "Error", w)
 		w.Serve()
 		return
@@ -433,7 +422,7 @@ func AddExistingWrapperSubmit(w *wrapper.Wrapper) {
 	err = parent.Save(w)
 	if err != nil {
 		errmessage := fmt.Sprintf("Unable to save parent element  by %s : %s", w.Request.Host, err.Error())
-		w.SiteConfig.Logger.Error(errmessage)
+		w.Logger.Error(errmessage)
 		services.AddMessage("Could not save parent element.", "Error", w)
 		w.Serve()
 		return
@@ -461,7 +450,7 @@ func AddExistingPathSubmit(w *wrapper.Wrapper) {
 	parent, err = paths.LoadPath(parentid, w)
 	if err != nil {
 		errmessage := fmt.Sprintf("Unable to loap parent element  by %s : %s", w.Request.Host, err.Error())
-		w.SiteConfig.Logger.Error(errmessage)
+		w.Logger.Error(errmessage)
 		services.AddMessage("Could not load parent element.", "Error", w)
 		w.Serve()
 		return
@@ -470,7 +459,7 @@ func AddExistingPathSubmit(w *wrapper.Wrapper) {
 	err = parent.Save(w)
 	if err != nil {
 		errmessage := fmt.Sprintf("Unable to save parent element  by %s : %s", w.Request.Host, err.Error())
-		w.SiteConfig.Logger.Error(errmessage)
+		w.Logger.Error(errmessage)
 		services.AddMessage("Could not save parent element.", "Error", w)
 		w.Serve()
 		return
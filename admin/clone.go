@@ -0,0 +1,115 @@
+package admin
+
+import (
+	"fmt"
+	"github.com/mongolar/mongolar/models/elements"
+	"github.com/mongolar/mongolar/models/paths"
+	"github.com/mongolar/mongolar/services"
+	"github.com/mongolar/mongolar/wrapper"
+)
+
+// Controller to copy an element, the parameters are the element id and "deep"
+// or "shallow".  Optionally a parent type ("wrapper" or "paths") and parent id
+// add the copy to a wrapper element or path.
+func CloneElement(w *wrapper.Wrapper) {
+	if len(w.APIParams) < 2 {
+		w.Error(403, "Forbidden")
+		return
+	}
+	id := w.APIParams[0]
+	deep := w.APIParams[1] == "deep"
+	nid, err := elements.Clone(id, deep, w)
+	if err != nil {
+		errmessage := fmt.Sprintf("Unable to clone element %s by %s : %s", id, w.Request.Host, err.Error())
+		w.Logger.Error(errmessage)
+		services.AddMessage("Could not copy the element.", "Error", w)
+		w.Serve()
+		return
+	}
+	w.SetPayload("id", nid)
+	if len(w.APIParams) < 4 {
+		services.AddMessage("You have copied the element.", "Success", w)
+		w.Serve()
+		return
+	}
+	parentid := w.APIParams[3]
+	dynamic := services.Dynamic{
+		Target:     parentid,
+		Controller: "admin/element",
+		Template:   "admin/element.html",
+		Id:         parentid,
+	}
+	switch w.APIParams[2] {
+	case "wrapper":
+		var parent elements.WrapperElement
+		parent, err = elements.LoadWrapperElement(parentid, w)
+		if err == nil {
+			parent.Elements = append(parent.Elements, nid)
+			err = parent.Save(w)
+		}
+	case "paths":
+		err = paths.AddChild(parentid, nid, w)
+		dynamic = services.Dynamic{
+			Target:     "centereditor",
+			Controller: "admin/path_elements",
+			Template:   "admin/path_elements.html",
+			Id:         parentid,
+		}
+	default:
+		w.Error(403, "Forbidden")
+		return
+	}
+	if err != nil {
+		errmessage := fmt.Sprintf("Unable to add clone %s to %s by %s : %s", nid, parentid, w.Request.Host, err.Error())
+		w.Logger.Error(errmessage)
+		services.AddMessage("The element was copied but could not be added.", "Error", w)
+		w.Serve()
+		return
+	}
+	services.SetDynamic(dynamic, w)
+	services.AddMessage("You have copied the element.", "Success", w)
+	w.Serve()
+	return
+}
+
+// Controller to copy a path and its elements to a new url, the parameters are
+// the path id and "deep" or "shallow".  The new url is the "path" query value
+// and defaults to the current url with "-copy" appended.
+func DuplicatePath(w *wrapper.Wrapper) {
+	if len(w.APIParams) < 2 {
+		w.Error(403, "Forbidden")
+		return
+	}
+	id := w.APIParams[0]
+	deep := w.APIParams[1] == "deep"
+	u := w.Request.URL.Query().Get("path")
+	if u == "" {
+		p, err := paths.LoadPath(id, w)
+		if err != nil {
+			errmessage := fmt.Sprintf("Unable to load path %s by %s : %s", id, w.Request.Host, err.Error())
+			w.Logger.Error(errmessage)
+			services.AddMessage("Could not load the path.", "Error", w)
+			w.Serve()
+			return
+		}
+		u = p.Path + "-copy"
+	}
+	p, err := paths.Duplicate(id, u, deep, w)
+	if err != nil {
+		errmessage := fmt.Sprintf("Unable to duplicate path %s by %s : %s", id, w.Request.Host, err.Error())
+		w.Logger.Error(errmessage)
+		services.AddMessage("Could not duplicate the path.", "Error", w)
+		w.Serve()
+		return
+	}
+	dynamic := services.Dynamic{
+		Target:     "pathbar",
+		Controller: "admin/paths",
+		Template:   "admin/path_list.html",
+	}
+	services.SetDynamic(dynamic, w)
+	w.SetPayload("id", p.MongoId.Hex())
+	services.AddMessage("You have duplicated the path as "+p.Path+".", "Success", w)
+	w.Serve()
+	return
+}
@@ -3,18 +3,19 @@ package admin
 import (
 	"fmt"
 	"github.com/mongolar/mongolar/form"
+	"github.com/mongolar/mongolar/metrics"
 	"github.com/mongolar/mongolar/models/contenttypes"
 	"github.com/mongolar/mongolar/models/elements"
+	"github.com/mongolar/mongolar/models/references"
 	"github.com/mongolar/mongolar/services"
 	"github.com/mongolar/mongolar/wrapper"
-	"net/http"
+	"gopkg.in/mgo.v2/bson"
 )
 
 // Controller to change content type for content type element.
 func ContentTypeEditor(w *wrapper.Wrapper) {
 	if len(w.APIParams) == 0 {
-		http.Error(w.Writer, "Forbidden", 403)
-		w.Serve()
+		w.Error(403, "Forbidden")
 		return
 	}
 	if w.Request.Method != "POST" {
@@ -31,7 +32,7 @@ func ContentTypeEditorForm(w *wrapper.Wrapper) {
 	e, err := elements.LoadContentElement(elementid, w)
 	if err != nil {
 		errmessage := fmt.Sprintf("Element not found to edit for %s by %s", elementid, w.Request.Host)
-		w.SiteConfig.Logger.Error(errmessage)
+		w.Logger.Error(errmessage)
 		services.AddMessage("This element was not found", "Error", w)
 		w.Serve()
 		return
@@ -40,7 +41,7 @@ func ContentTypeEditorForm(w *wrapper.Wrapper) {
 	cts, err = contenttypes.AllContentTypes(w)
 	if err != nil {
 		errmessage := fmt.Sprintf("Unable to query all Content Types: %s", err.Error())
-		w.SiteConfig.Logger.Error(errmessage)
+		w.Logger.Error(errmessage)
 		services.AddMessage("Unable to retrieve content types.", "Error", w)
 		w.Serve()
 		return
@@ -71,7 +72,7 @@ func ContentTypeEditorSubmit(w *wrapper.Wrapper) {
 	e, err := elements.LoadContentElement(elementid, w)
 	if err != nil {
 		errmessage := fmt.Sprintf("Element not found to edit for %s by %s", elementid, w.Request.Host)
-		w.SiteConfig.Logger.Error(errmessage)
+		w.Logger.Error(errmessage)
 		services.AddMessage("This element was not found", "Error", w)
 		w.Serve()
 		return
@@ -88,7 +89,7 @@ func ContentTypeEditorSubmit(w *wrapper.Wrapper) {
 	err = e.Save(w)
 	if err != nil {
 		errmessage := fmt.Sprintf("Element not saved %s by %s", w.APIParams[0], w.Request.Host)
-		w.SiteConfig.Logger.Error(errmessage)
+		w.Logger.Error(errmessage)
 		services.AddMessage("Unable to save element.", "Error", w)
 	} else {
 		services.AddMessage("Element content type saved.", "Success", w)
@@ -100,8 +101,7 @@ func ContentTypeEditorSubmit(w *wrapper.Wrapper) {
 // Controller to edit content in element.
 func ContentEditor(w *wrapper.Wrapper) {
 	if len(w.APIParams) == 0 {
-		http.Error(w.Writer, "Forbidden", 403)
-		w.Serve()
+		w.Error(403, "Forbidden")
 		return
 	}
 	if w.Request.Method != "POST" {
@@ -118,14 +118,14 @@ func ContentEditorForm(w *wrapper.Wrapper) {
 	e, err := elements.LoadContentElement(elementid, w)
 	if err != nil {
 		errmessage := fmt.Sprintf("Element not found to edit for %s by %s", elementid, w.Request.Host)
-		w.SiteConfig.Logger.Error(errmessage)
+		w.Logger.Error(errmessage)
 		services.AddMessage("This element was not found", "Error", w)
 		w.Serve()
 		return
 	}
 	if e.ContentValues.Type == "" {
-		errmessage := fmt.Sprintf("No content type set for %s", elementid, w.Request.Host)
-		w.SiteConfig.Logger.Error(errmessage)
+		errmessage := fmt.Sprintf("No content type set for %s by %s", elementid, w.Request.Host)
+		w.Logger.Error(errmessage)
 		services.AddMessage("This element doesn't have a content type set.  Set a content type to edit values.", "Error", w)
 		w.Serve()
 		return
@@ -134,13 +134,20 @@ func ContentEditorForm(w *wrapper.Wrapper) {
 	ct, err = contenttypes.LoadContentTypeT(e.ContentValues.Type, w)
 	if err != nil {
 		errmessage := fmt.Sprintf("Unable to find content type %s : %s", e.ContentValues.Type, err.Error())
-		w.SiteConfig.Logger.Error(errmessage)
+		w.Logger.Error(errmessage)
 		services.AddMessage("Unable to find content type.", "Error", w)
 		w.Serve()
 		return
 	}
 	f := form.NewForm()
-	f.Fields = ct.Form
+	f.Fields, err = referenceOptions(ct.Form, w)
+	if err != nil {
+		errmessage := fmt.Sprintf("Unable to load reference options for %s : %s", e.ContentValues.Type, err.Error())
+		w.Logger.Error(errmessage)
+		services.AddMessage("Unable to load elements to reference.", "Error", w)
+		w.Serve()
+		return
+	}
 	f.FormData = e.ContentValues.Content
 	f.Register(w)
 	w.SetTemplate("admin/form.html")
@@ -153,23 +160,53 @@ func ContentEditorForm(w *wrapper.Wrapper) {
 func ContentEditorSubmit(w *wrapper.Wrapper) {
 	elementid := w.APIParams[0]
 	e, err := elements.LoadContentElement(elementid, w)
+	if err != nil {
+		errmessage := fmt.Sprintf("Element not found to edit for %s by %s", elementid, w.Request.Host)
+		w.Logger.Error(errmessage)
+		services.AddMessage("This element was not found", "Error", w)
+		w.Serve()
+		return
+	}
 	post := make(map[string]interface{})
 	err = form.GetValidFormData(w, &post)
 	if err != nil {
 		return
 	}
-	e.ContentValues.Content = post
-	delete(e.ContentValues.Content, "mongolartype")
-	delete(e.ContentValues.Content, "mongolarid")
-	delete(e.ContentValues.Content, "form_id")
+	var ct contenttypes.ContentType
+	ct, err = contenttypes.LoadContentTypeT(e.ContentValues.Type, w)
+	if err != nil {
+		errmessage := fmt.Sprintf("Unable to find content type %s : %s", e.ContentValues.Type, err.Error())
+		w.Logger.Error(errmessage)
+		services.AddMessage("Unable to find content type.", "Error", w)
+		w.Serve()
+		return
+	}
+	content, invalid := ct.Validate(post)
+	for k, message := range ct.ValidateReferences(content, w) {
+		invalid[k] = message
+	}
+	if len(invalid) > 0 {
+		metrics.FormFailures.Inc(w.SiteConfig.Name, "content")
+		for _, message := range invalid {
+			services.AddMessage(message, "Error", w)
+		}
+		w.Serve()
+		return
+	}
+	e.ContentValues.Content = content
 	err = e.Save(w)
 	if err != nil {
 		errmessage := fmt.Sprintf("Element not saved %s by %s", w.APIParams[0], w.Request.Host)
-		w.SiteConfig.Logger.Error(errmessage)
+		w.Logger.Error(errmessage)
 		services.AddMessage("Unable to save element.", "Error", w)
 		w.Serve()
 		return
 	}
+	err = references.Set(elementid, ct.References(content), w)
+	if err != nil {
+		errmessage := fmt.Sprintf("Unable to index references for %s : %s", elementid, err.Error())
+		w.Logger.Error(errmessage)
+	}
 	services.AddMessage("Element content saved.", "Success", w)
 	dynamic := services.Dynamic{
 		Target:     elementid,
@@ -182,3 +219,53 @@ func ContentEditorSubmit(w *wrapper.Wrapper) {
 	return
 
 }
+
+// Reference fields are rendered as selects of the content elements they may reference.
+func referenceOptions(fields []*form.Field, w *wrapper.Wrapper) ([]*form.Field, error) {
+	rendered := make([]*form.Field, 0)
+	for _, field := range fields {
+		if field.Type == "repeatSection" && field.TemplateOptions != nil {
+			nested, err := referenceOptions(field.TemplateOptions.Fields, w)
+			if err != nil {
+				return nil, err
+			}
+			to := *field.TemplateOptions
+			to.Fields = nested
+			rf := *field
+			rf.TemplateOptions = &to
+			field = &rf
+		}
+		if field.Type != "reference" {
+			rendered = append(rendered, field)
+			continue
+		}
+		// Imported content types may not have template options.
+		to := form.TemplateOptions{}
+		if field.TemplateOptions != nil {
+			to = *field.TemplateOptions
+		}
+		s := bson.M{"controller": "content"}
+		if to.ContentType != "" {
+			s["controller_values.type"] = to.ContentType
+		}
+		var es []elements.Element
+		c := w.DbSession.DB("").C("elements")
+		err := c.Find(s).Select(bson.M{"title": 1}).Sort("title").All(&es)
+		if err != nil {
+			return nil, err
+		}
+		opts := make([]map[string]string, 0)
+		for _, e := range es {
+			opts = append(opts, map[string]string{"name": e.Title, "value": e.MongoId.Hex()})
+		}
+		to.Options = opts
+		rf := *field
+		rf.TemplateOptions = &to
+		rf.Type = "select"
+		if to.Multiple {
+			rf.Type = "multiCheckbox"
+		}
+		rendered = append(rendered, &rf)
+	}
+	return rendered, nil
+}
@@ -4,11 +4,11 @@ package admin
 import (
 	"fmt"
 	"github.com/mongolar/mongolar/form"
+	"github.com/mongolar/mongolar/listing"
+	"github.com/mongolar/mongolar/models/contenttypes"
 	"github.com/mongolar/mongolar/services"
 	"github.com/mongolar/mongolar/wrapper"
 	"gopkg.in/mgo.v2/bson"
-	"net/http"
-	"reflect"
 	"strings"
 )
 
@@ -19,13 +19,17 @@ type ContentType struct {
 }
 
 func GetContentType(w *wrapper.Wrapper) {
+	if len(w.APIParams) < 1 || !bson.IsObjectIdHex(w.APIParams[0]) {
+		w.Error(403, "Forbidden")
+		return
+	}
 	c := w.DbSession.DB("").C("content_types")
 	i := bson.M{"_id": bson.ObjectIdHex(w.APIParams[0])}
 	var ct ContentType
 	err := c.Find(i).One(&ct)
 	if err != nil {
 		errmessage := fmt.Sprintf("Content Type not found %s : %s", w.APIParams[0], err.Error())
-		w.SiteConfig.Logger.Error(errmessage)
+		w.Logger.Error(errmessage)
 		services.AddMessage("Your content types was not found.", "Error", w)
 		w.Serve()
 		return
@@ -37,8 +41,7 @@ func GetContentType(w *wrapper.Wrapper) {
 
 func EditContentType(w *wrapper.Wrapper) {
 	if len(w.APIParams) < 1 {
-		http.Error(w.Writer, "Forbidden", 403)
-		w.Serve()
+		w.Error(403, "Forbidden")
 		return
 	}
 	if w.Request.Method != "POST" {
@@ -53,27 +56,42 @@ func EditContentTypeForm(w *wrapper.Wrapper) {
 	f := form.NewForm()
 	ct := new(ContentType)
 	if w.APIParams[0] != "new" {
+		if !bson.IsObjectIdHex(w.APIParams[0]) {
+			w.Error(403, "Forbidden")
+			return
+		}
 		c := w.DbSession.DB("").C("content_types")
 		i := bson.M{"_id": bson.ObjectIdHex(w.APIParams[0])}
 		err := c.Find(i).One(ct)
 		if err != nil {
 			errmessage := fmt.Sprintf("Content Type not found %s : %s", w.APIParams[0], err.Error())
-			w.SiteConfig.Logger.Error(errmessage)
+			w.Logger.Error(errmessage)
 			services.AddMessage("Your content types was not found ", "Error", w)
 			w.Serve()
 			return
 		}
 		var elements []map[string]interface{}
 		for _, field := range ct.Form {
+			// Imported content types may not have template options.
+			to := field.TemplateOptions
+			if to == nil {
+				to = &form.TemplateOptions{}
+			}
 			element := make(map[string]interface{})
 			element["type"] = field.Type
+			if field.Type == "input" && to.Type == "number" {
+				element["type"] = "number"
+			}
 			element["key"] = field.Key
-			element["label"] = field.TemplateOptions.Label
-			element["placeholder"] = field.TemplateOptions.Placeholder
-			element["rows"] = field.TemplateOptions.Rows
-			element["cols"] = field.TemplateOptions.Cols
+			element["label"] = to.Label
+			element["placeholder"] = to.Placeholder
+			element["rows"] = to.Rows
+			element["cols"] = to.Cols
+			element["required"] = to.Required
+			element["content_type"] = to.ContentType
+			element["multiple"] = to.Multiple
 			element["options"] = ""
-			for _, opt := range field.TemplateOptions.Options {
+			for _, opt := range to.Options {
 				element["options"] = fmt.Sprintf("%s%s|%s\n", element["options"], opt["name"], opt["value"])
 			}
 			elements = append(elements, element)
@@ -105,24 +123,48 @@ func EditContentTypeSubmit(w *wrapper.Wrapper) {
 	if err != nil {
 		return
 	}
-	elements := reflect.ValueOf(post["elements"])
+	mongolarid, _ := post["mongolarid"].(string)
+	contenttype, _ := post["content_type"].(string)
+	elements, ok := post["elements"].([]interface{})
+	if !ok || contenttype == "" || (mongolarid != "new" && !bson.IsObjectIdHex(mongolarid)) {
+		errmessage := fmt.Sprintf("Invalid content type submitted for %s by %s", mongolarid, w.Request.Host)
+		w.Logger.Error(errmessage)
+		services.AddMessage("Your content type could not be read.", "Error", w)
+		w.Serve()
+		return
+	}
 	f := form.NewForm()
-	for i := 0; i < elements.Len(); i++ {
+	for _, e := range elements {
 		var field *form.Field
-		element := elements.Index(i).Interface().(map[string]interface{})
-		switch element["type"].(string) {
+		element, ok := e.(map[string]interface{})
+		if !ok {
+			services.AddMessage("Your content type fields could not be read.", "Error", w)
+			w.Serve()
+			return
+		}
+		key, _ := element["key"].(string)
+		fieldtype, _ := element["type"].(string)
+		if key == "" {
+			services.AddMessage("Every field needs a key.", "Error", w)
+			w.Serve()
+			return
+		}
+		switch fieldtype {
 		case "input":
-			field = f.AddText(element["key"].(string), "text")
+			field = f.AddText(key, "text")
+		case "number":
+			field = f.AddText(key, "number")
 		case "textarea":
-			field = f.AddTextArea(element["key"].(string))
+			field = f.AddTextArea(key)
 		case "radio":
-			values := strings.Split(element["options"].(string), "\n")
+			options, _ := element["options"].(string)
+			values := strings.Split(options, "\n")
 			opt := make([]map[string]string, 0)
 			for _, value := range values {
 				namval := strings.Split(value, "|")
 				if len(namval) < 2 {
 					errmessage := fmt.Sprintf("Attempt to set incorrect form option %s by %s", value, w.Request.Host)
-					w.SiteConfig.Logger.Error(errmessage)
+					w.Logger.Error(errmessage)
 					services.AddMessage("Your options must be of the format Name|Value", "Error", w)
 					w.Serve()
 					return
@@ -133,49 +175,53 @@ func EditContentTypeSubmit(w *wrapper.Wrapper) {
 				}
 				opt = append(opt, newval)
 			}
-			field = f.AddRadio(element["key"].(string), opt)
+			field = f.AddRadio(key, opt)
 		case "checkbox":
-			field = f.AddCheckBox(element["key"].(string))
+			field = f.AddCheckBox(key)
+		case "reference":
+			ct, _ := element["content_type"].(string)
+			multiple, _ := element["multiple"].(bool)
+			field = f.AddReference(key, ct, multiple)
 		default:
-			//TODO messaging and logging
+			errmessage := fmt.Sprintf("Attempt to set unknown field type %s by %s", fieldtype, w.Request.Host)
+			w.Logger.Error(errmessage)
+			services.AddMessage("Unknown field type "+fieldtype, "Error", w)
+			w.Serve()
 			return
 		}
-		if _, ok := element["label"]; ok {
-			if element["label"].(string) != "" {
-				field.AddLabel(element["label"].(string))
-			}
+		if label, ok := element["label"].(string); ok && label != "" {
+			field.AddLabel(label)
 		}
-		if _, ok := element["placeholder"]; ok {
-			if element["placeholder"].(string) != "" {
-				field.AddPlaceHolder(element["placeholder"].(string))
-			}
+		if placeholder, ok := element["placeholder"].(string); ok && placeholder != "" {
+			field.AddPlaceHolder(placeholder)
 		}
-		if _, ok := element["rows"]; ok {
-			if _, ok := element["cols"]; ok {
-				if element["rows"].(float64) != 0 && element["cols"] != 0 {
-					field.AddRowsCols(int(element["rows"].(float64)), int(element["cols"].(float64)))
-				}
-			}
+		if required, ok := element["required"].(bool); ok && required {
+			field.Required()
+		}
+		rows, _ := element["rows"].(float64)
+		cols, _ := element["cols"].(float64)
+		if rows != 0 && cols != 0 {
+			field.AddRowsCols(int(rows), int(cols))
 		}
 	}
 
 	var id bson.ObjectId
-	if post["mongolarid"].(string) == "new" {
+	if mongolarid == "new" {
 		id = bson.NewObjectId()
 	} else {
-		id = bson.ObjectIdHex(post["mongolarid"].(string))
+		id = bson.ObjectIdHex(mongolarid)
 	}
 	ct := ContentType{
 		Form:    f.Fields,
-		Type:    post["content_type"].(string),
+		Type:    contenttype,
 		MongoId: id,
 	}
 	s := bson.M{"_id": id}
 	c := w.DbSession.DB("").C("content_types")
 	_, err = c.Upsert(s, ct)
 	if err != nil {
-		errmessage := fmt.Sprintf("Cannnot save content type %s : %s", post["mongolarid"].(string), err.Error())
-		w.SiteConfig.Logger.Error(errmessage)
+		errmessage := fmt.Sprintf("Cannnot save content type %s : %s", mongolarid, err.Error())
+		w.Logger.Error(errmessage)
 		services.AddMessage("Unable to save content type.", "Error", w)
 		w.Serve()
 		return
@@ -194,16 +240,17 @@ func EditContentTypeSubmit(w *wrapper.Wrapper) {
 
 func GetAllContentTypes(w *wrapper.Wrapper) {
 	c := w.DbSession.DB("").C("content_types")
-	var cts []ContentType
-	err := c.Find(nil).Limit(50).Iter().All(&cts)
+	cts := make([]ContentType, 0)
+	p, err := listing.New(w, contenttypes.Listing).Run(c, &cts)
 	if err != nil {
 		errmessage := fmt.Sprintf("Unable to retrieve a list of content types.")
-		w.SiteConfig.Logger.Error(errmessage)
+		w.Logger.Error(errmessage)
 		services.AddMessage("Unable to retrieve a list of elements.", "Error", w)
 		w.Serve()
 		return
 	}
 	w.SetPayload("content_types", cts)
+	w.SetPayload("page", p)
 	w.Serve()
 	return
 }
@@ -211,17 +258,22 @@ func GetAllContentTypes(w *wrapper.Wrapper) {
 func FieldFormGroup() []*form.Field {
 	ft := []map[string]string{
 		map[string]string{"name": "Text Field", "value": "input"},
+		map[string]string{"name": "Number Field", "value": "number"},
 		map[string]string{"name": "TextArea Field", "value": "textarea"},
 		map[string]string{"name": "Radio Buttons", "value": "radio"},
 		map[string]string{"name": "Checkbox", "value": "checkbox"},
+		map[string]string{"name": "Reference", "value": "reference"},
 	}
 	f := form.NewForm()
 	f.AddRadio("type", ft).AddLabel("Field Type").Required()
 	f.AddText("key", "text").AddLabel("Key").Required()
 	f.AddText("label", "text").AddLabel("Label")
+	f.AddCheckBox("required").AddLabel("Required")
 	f.AddText("placeholder", "text").AddLabel("Placeholder")
 	f.AddTextArea("options").AddLabel("Options")
 	f.AddText("cols", "text").AddLabel("Columns")
 	f.AddText("rows", "text").AddLabel("Rows")
+	f.AddText("content_type", "text").AddLabel("Referenced Content Type")
+	f.AddCheckBox("multiple").AddLabel("Multiple References")
 	return f.Fields
 }
@@ -0,0 +1,76 @@
+package admin
+
+import (
+	"fmt"
+	"github.com/mongolar/mongolar/models/contenttypes"
+	"github.com/mongolar/mongolar/services"
+	"github.com/mongolar/mongolar/wrapper"
+	"io/ioutil"
+)
+
+// Controller to download all content types as a YAML or JSON file.
+func ExportContentTypes(w *wrapper.Wrapper) {
+	format := "yaml"
+	if len(w.APIParams) > 0 {
+		format = w.APIParams[0]
+	}
+	b, err := contenttypes.ExportContentTypes(format, w)
+	if err != nil {
+		errmessage := fmt.Sprintf("Unable to export content types by %s: %s", w.Request.Host, err.Error())
+		w.Logger.Error(errmessage)
+		services.AddMessage("Unable to export content types.", "Error", w)
+		w.Serve()
+		return
+	}
+	contenttype := "application/x-yaml"
+	if format == "json" {
+		contenttype = "application/json"
+	}
+	w.ServeAttachment("content_types."+format, contenttype, b)
+	return
+}
+
+// Controller to import content types from a posted YAML or JSON file.
+// The import mode (diff, merge or replace) is the first parameter, the default
+// is diff so nothing is changed until the editor has seen the changes.
+func ImportContentTypes(w *wrapper.Wrapper) {
+	if w.Request.Method != "POST" {
+		w.Error(403, "Forbidden")
+		return
+	}
+	mode := contenttypes.ImportDiff
+	if len(w.APIParams) > 0 {
+		mode = w.APIParams[0]
+	}
+	b, err := ioutil.ReadAll(w.Request.Body)
+	if err != nil {
+		errmessage := fmt.Sprintf("Unable to read content type import by %s: %s", w.Request.Host, err.Error())
+		w.Logger.Error(errmessage)
+		services.AddMessage("Unable to read your import.", "Error", w)
+		w.Serve()
+		return
+	}
+	format := w.Request.URL.Query().Get("format")
+	changes, err := contenttypes.ImportContentTypes(b, format, mode, w)
+	w.SetPayload("changes", changes)
+	if err != nil {
+		errmessage := fmt.Sprintf("Unable to import content types by %s: %s", w.Request.Host, err.Error())
+		w.Logger.Error(errmessage)
+		services.AddMessage("Unable to import content types.", "Error", w)
+		w.Serve()
+		return
+	}
+	if mode == contenttypes.ImportDiff {
+		services.AddMessage("Nothing was saved, these are the changes an import would make.", "Info", w)
+	} else {
+		services.AddMessage("Content types imported.", "Success", w)
+		dynamic := services.Dynamic{
+			Target:     "contenttypelist",
+			Controller: "admin/all_content_types",
+			Template:   "admin/content_type_list.html",
+		}
+		services.SetDynamic(dynamic, w)
+	}
+	w.Serve()
+	return
+}
@@ -2,11 +2,10 @@ package admin
 
 import (
 	"fmt"
-	"github.com/mongolar/mongolar/models/elements"
-	"github.com/mongolar/mongolar/models/paths"
+	"github.com/mongolar/mongolar/models/references"
+	"github.com/mongolar/mongolar/models/trash"
 	"github.com/mongolar/mongolar/services"
 	"github.com/mongolar/mongolar/wrapper"
-	"net/http"
 )
 
 // This controller deletes paths and elements
@@ -15,8 +14,7 @@ func Delete(w *wrapper.Wrapper) {
 	if len(w.APIParams) > 1 {
 		parenttype = w.APIParams[0]
 	} else {
-		http.Error(w.Writer, "Forbidden", 403)
-		w.Serve()
+		w.Error(403, "Forbidden")
 		return
 	}
 	w.Shift()
@@ -28,19 +26,18 @@ func Delete(w *wrapper.Wrapper) {
 		DeletePath(w)
 		return
 	default:
-		http.Error(w.Writer, "Forbidden", 403)
-		w.Serve()
+		w.Error(403, "Forbidden")
 	}
 	return
 }
 
-// Controller to delete a path
+// Controller to move a path to the trash
 func DeletePath(w *wrapper.Wrapper) {
 	id := w.APIParams[0]
-	err := paths.Delete(id, w)
+	err := trash.TrashPath(id, w)
 	if err != nil {
 		errmessage := fmt.Sprintf("Unable to delete path %s : %s", id, err.Error())
-		w.SiteConfig.Logger.Error(errmessage)
+		w.Logger.Error(errmessage)
 		services.AddMessage("Unable to delete.", "Error", w)
 		w.Serve()
 		return
@@ -51,44 +48,44 @@ func DeletePath(w *wrapper.Wrapper) {
 		Template:   "admin/path_list.html",
 	}
 	services.SetDynamic(dynamic, w)
-	services.AddMessage("Successfully deleted path", "Success", w)
+	services.AddMessage("Successfully moved path to the trash", "Success", w)
 	w.Serve()
 	return
 }
 
-// Controller to delete an element and all references to the element
+// Controller to move an element to the trash and remove all references to the element
+// If other content references the element the delete has to be confirmed
+// by calling the controller again with "confirm" appended.
 func DeleteElement(w *wrapper.Wrapper) {
 	id := w.APIParams[0]
-	err := elements.Delete(id, w)
+	confirmed := len(w.APIParams) > 1 && w.APIParams[1] == "confirm"
+	dependents, err := references.Dependents(id, w)
 	if err != nil {
-		errmessage := fmt.Sprintf("Unable to delete %s : %s", id, err.Error())
-		w.SiteConfig.Logger.Error(errmessage)
-		services.AddMessage("Unable to delete element.", "Error", w)
+		errmessage := fmt.Sprintf("Unable to retrieve dependents of %s : %s", id, err.Error())
+		w.Logger.Error(errmessage)
 	}
-	err = elements.WrapperDeleteAllChild(id, w)
-	if err != nil {
-		errmessage := fmt.Sprintf("Unable to delete reference to element %s : %s", id, err.Error())
-		w.SiteConfig.Logger.Error(errmessage)
-		services.AddMessage("Unable to delete all references to your element.", "Error", w)
-	}
-	err = elements.SlugDeleteAllChild(id, w)
-	if err != nil {
-		errmessage := fmt.Sprintf("Unable to delete reference to element %s : %s", id, err.Error())
-		w.SiteConfig.Logger.Error(errmessage)
-		services.AddMessage("Unable to delete all references to your element.", "Error", w)
+	if len(dependents) > 0 && !confirmed {
+		message := fmt.Sprintf("This element is referenced by %d other elements, delete again to confirm.", len(dependents))
+		services.AddMessage(message, "Warning", w)
+		w.SetPayload("dependents", dependents)
+		w.SetPayload("confirm", "admin/delete/elements/"+id+"/confirm")
+		w.Serve()
+		return
 	}
-	err = paths.DeleteAllChild(id, w)
+	err = trash.TrashElement(id, w)
 	if err != nil {
-		errmessage := fmt.Sprintf("Unable to delete reference to %s : %s", id, err.Error())
-		w.SiteConfig.Logger.Error(errmessage)
-		services.AddMessage("Unable to delete all references to your element.", "Error", w)
+		errmessage := fmt.Sprintf("Unable to delete %s : %s", id, err.Error())
+		w.Logger.Error(errmessage)
+		services.AddMessage("Unable to delete element.", "Error", w)
+		w.Serve()
+		return
 	}
 	dynamic := services.Dynamic{
 		Target:   id,
 		Template: "default.html",
 	}
 	services.SetDynamic(dynamic, w)
-	services.AddMessage("Successfully deleted element", "Success", w)
+	services.AddMessage("Successfully moved element to the trash", "Success", w)
 	w.Serve()
 	return
 }
@@ -3,11 +3,14 @@ package admin
 import (
 	"fmt"
 	"github.com/mongolar/mongolar/form"
+	"github.com/mongolar/mongolar/listing"
 	"github.com/mongolar/mongolar/models/elements"
+	"github.com/mongolar/mongolar/models/tree"
 	"github.com/mongolar/mongolar/services"
 	"github.com/mongolar/mongolar/wrapper"
 	"gopkg.in/mgo.v2/bson"
-	"net/http"
+	"strconv"
+	"time"
 	"unicode"
 )
 
@@ -16,7 +19,7 @@ func Element(w *wrapper.Wrapper) {
 	e := elements.NewElement()
 	var id string
 	if len(w.APIParams) == 0 {
-		http.Error(w.Writer, "Forbidden", 403)
+		w.Error(403, "Forbidden")
 		return
 	} else {
 		id = w.APIParams[0]
@@ -24,7 +27,7 @@ func Element(w *wrapper.Wrapper) {
 	err := elements.GetById(id, &e, w)
 	if err != nil {
 		errmessage := fmt.Sprintf("Element not found to edit for %s by %s.", id, w.Request.Host)
-		w.SiteConfig.Logger.Error(errmessage)
+		w.Logger.Error(errmessage)
 		services.AddMessage("This element was not found", "Error", w)
 	} else {
 		w.SetPayload("mongolarid", e.MongoId.Hex())
@@ -35,7 +38,7 @@ func Element(w *wrapper.Wrapper) {
 			we, err := elements.LoadWrapperElement(id, w)
 			if err != nil {
 				errmessage := fmt.Sprintf("Element not found to edit for %s by %s.", id, w.Request.Host)
-				w.SiteConfig.Logger.Error(errmessage)
+				w.Logger.Error(errmessage)
 				services.AddMessage("This element was not found", "Error", w)
 			}
 			w.SetPayload("elements", we.Elements)
@@ -44,11 +47,17 @@ func Element(w *wrapper.Wrapper) {
 			we, err := elements.LoadSlugElement(id, w)
 			if err != nil {
 				errmessage := fmt.Sprintf("Element not found to edit for %s by %s.", id, w.Request.Host)
-				w.SiteConfig.Logger.Error(errmessage)
+				w.Logger.Error(errmessage)
 				services.AddMessage("This element was not found", "Error", w)
 			}
 			w.SetPayload("elements", we.Slugs)
 		}
+		chains, err := tree.WhereUsed(id, w)
+		if err != nil {
+			errmessage := fmt.Sprintf("Unable to find where %s is used by %s: %s", id, w.Request.Host, err.Error())
+			w.Logger.Error(errmessage)
+		}
+		w.SetPayload("where_used", chains)
 	}
 	w.Serve()
 }
@@ -60,7 +69,8 @@ func ElementEditor(w *wrapper.Wrapper) {
 	if len(w.APIParams) > 0 {
 		elementid = w.APIParams[0]
 	} else {
-		http.Error(w.Writer, "Forbidden", 403)
+		w.Error(403, "Forbidden")
+		return
 	}
 	if w.Request.Method != "POST" {
 		f := form.NewForm()
@@ -76,25 +86,27 @@ func ElementEditor(w *wrapper.Wrapper) {
 		f.AddText("template", "text").AddLabel("Template")
 		f.AddText("dyn", "text").AddLabel("Dynamic Id")
 		f.AddText("classes", "text").AddLabel("Classes")
+		f.AddText("cache_max_age", "text").AddLabel("Cache seconds (0 for the site default, -1 for none)")
 		f.AddText("id", "text").Hidden()
 		if elementid != "new" {
 			e := elements.NewElement()
 			err := elements.GetById(elementid, &e, w)
 			if err != nil {
-				errmessage := fmt.Sprintf("Element not found to edit for %s by %s", elementid, w.Request.Host, err.Error())
-				w.SiteConfig.Logger.Error(errmessage)
+				errmessage := fmt.Sprintf("Element not found to edit for %s by %s: %s", elementid, w.Request.Host, err.Error())
+				w.Logger.Error(errmessage)
 				services.AddMessage("This element was not found", "Error", w)
 				w.Serve()
 				return
 			}
 			// Have to do this for namespacing stuff on the AngularJs side.
 			data := map[string]string{
-				"controller": e.Controller,
-				"template":   e.Template,
-				"dyn":        e.DynamicId,
-				"classes":    e.Classes,
-				"id":         e.MongoId.Hex(),
-				"title":      e.Title,
+				"controller":    e.Controller,
+				"template":      e.Template,
+				"dyn":           e.DynamicId,
+				"classes":       e.Classes,
+				"cache_max_age": strconv.Itoa(e.CacheMaxAge),
+				"id":            e.MongoId.Hex(),
+				"title":         e.Title,
 			}
 			f.FormData = data
 		}
@@ -108,38 +120,48 @@ func ElementEditor(w *wrapper.Wrapper) {
 			return
 		} else {
 			c := w.DbSession.DB("").C("elements")
+			maxage, _ := strconv.Atoi(post["cache_max_age"])
 			if post["mongolarid"] == "new" {
 				p := elements.Element{
-					Controller: post["controller"],
-					DynamicId:  post["dyn"],
-					Template:   post["template"],
-					Title:      post["title"],
-					Classes:    post["classes"],
+					Controller:  post["controller"],
+					DynamicId:   post["dyn"],
+					Template:    post["template"],
+					Title:       post["title"],
+					Classes:     post["classes"],
+					Updated:     time.Now(),
+					CacheMaxAge: maxage,
 				}
 				err := c.Insert(p)
 				if err != nil {
 					errmessage := fmt.Sprintf("Unable to save new element by %s : %s", w.Request.Host, err.Error())
-					w.SiteConfig.Logger.Error(errmessage)
+					w.Logger.Error(errmessage)
 					services.AddMessage("There was a problem saving your element.", "Error", w)
 				} else {
 					services.AddMessage("Your element was saved.", "Success", w)
 				}
+			} else if !bson.IsObjectIdHex(post["mongolarid"]) {
+				errmessage := fmt.Sprintf("Invalid element id %s by %s", post["mongolarid"], w.Request.Host)
+				w.Logger.Error(errmessage)
+				services.AddMessage("There was a problem saving your element.", "Error", w)
 			} else {
 				p := bson.M{
 					"$set": bson.M{
-						"template":   post["template"],
-						"title":      post["title"],
-						"dynamic_id": post["dyn"],
-						"controller": post["controller"],
-						"classes":    post["classes"],
+						"template":      post["template"],
+						"title":         post["title"],
+						"dynamic_id":    post["dyn"],
+						"controller":    post["controller"],
+						"classes":       post["classes"],
+						"cache_max_age": maxage,
 					},
 				}
+				p = elements.Stamp(p)
 				s := bson.M{"_id": bson.ObjectIdHex(post["mongolarid"])}
 				err := c.Update(s, p)
+				elements.Invalidate(w, post["mongolarid"])
 				if err != nil {
 					errmessage := fmt.Sprintf("Unable to save element %s by %s : %s",
 						post["mongolarid"], w.Request.Host, err.Error())
-					w.SiteConfig.Logger.Error(errmessage)
+					w.Logger.Error(errmessage)
 					services.AddMessage("There was a problem saving your element.", "Error", w)
 				} else {
 					services.AddMessage("Your element was saved.", "Success", w)
@@ -160,17 +182,16 @@ func ElementEditor(w *wrapper.Wrapper) {
 
 // Controller to list all elements
 func AllElements(w *wrapper.Wrapper) {
-	c := w.DbSession.DB("").C("elements")
-	var es []elements.Element
-	err := c.Find(nil).Limit(50).Iter().All(&es)
+	es, p, err := elements.ElementPage(listing.New(w, elements.Listing), w)
 	if err != nil {
 		errmessage := fmt.Sprintf("Unable to retrieve a list of all elements: %s", err.Error())
-		w.SiteConfig.Logger.Error(errmessage)
+		w.Logger.Error(errmessage)
 		services.AddMessage("There was a problem retrieving the element list.", "Error", w)
 		w.Serve()
 		return
 	}
 	w.SetPayload("elements", es)
+	w.SetPayload("page", p)
 	w.Serve()
 	return
 }
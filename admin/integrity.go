@@ -0,0 +1,51 @@
+package admin
+
+import (
+	"fmt"
+	"github.com/mongolar/mongolar/integrity"
+	"github.com/mongolar/mongolar/services"
+	"github.com/mongolar/mongolar/wrapper"
+)
+
+// Controller to report integrity problems of the site.
+// Posting to the controller with "repair" as the first parameter repairs them.
+func Integrity(w *wrapper.Wrapper) {
+	repair := len(w.APIParams) > 0 && w.APIParams[0] == "repair"
+	if repair && w.Request.Method != "POST" {
+		w.Error(403, "Forbidden")
+		return
+	}
+	var r integrity.Report
+	var err error
+	if repair {
+		r, err = integrity.Repair(w)
+	} else {
+		r, err = integrity.Check(w)
+	}
+	if err != nil {
+		errmessage := fmt.Sprintf("Unable to check integrity by %s: %s", w.Request.Host, err.Error())
+		w.Logger.Error(errmessage)
+		services.AddMessage("Unable to check the integrity of the site.", "Error", w)
+		w.Serve()
+		return
+	}
+	for _, e := range r.Errors {
+		errmessage := fmt.Sprintf("Integrity repair by %s: %s", w.Request.Host, e)
+		w.Logger.Error(errmessage)
+	}
+	switch {
+	case len(r.Errors) > 0:
+		services.AddMessage("Some problems could not be repaired.", "Error", w)
+	case len(r.Problems) == 0:
+		services.AddMessage("No problems found.", "Success", w)
+	case repair:
+		services.AddMessage("Problems repaired, duplicate paths and invalid values have to be fixed by hand.", "Success", w)
+	default:
+		message := fmt.Sprintf("Found %d problems.", len(r.Problems))
+		services.AddMessage(message, "Warning", w)
+	}
+	w.SetPayload("integrity", r)
+	w.SetTemplate("admin/integrity_report.html")
+	w.Serve()
+	return
+}
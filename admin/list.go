@@ -0,0 +1,131 @@
+package admin
+
+import (
+	"fmt"
+	"github.com/mongolar/mongolar/form"
+	"github.com/mongolar/mongolar/models/contenttypes"
+	"github.com/mongolar/mongolar/models/elements"
+	"github.com/mongolar/mongolar/services"
+	"github.com/mongolar/mongolar/wrapper"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Controller to edit the query of a list element
+func ListEditor(w *wrapper.Wrapper) {
+	if len(w.APIParams) == 0 {
+		w.Error(403, "Forbidden")
+		return
+	}
+	if w.Request.Method != "POST" {
+		ListEditorForm(w)
+		return
+	}
+	ListEditorSubmit(w)
+	return
+}
+
+// Controller to present the list query form, filters are edited as one
+// "field=value" per line.
+func ListEditorForm(w *wrapper.Wrapper) {
+	listid := w.APIParams[0]
+	le, err := elements.LoadListElement(listid, w)
+	if err != nil {
+		errmessage := fmt.Sprintf("Element not found to edit for %s by %s", listid, w.Request.Host)
+		w.Logger.Error(errmessage)
+		services.AddMessage("This element was not found", "Error", w)
+		w.Serve()
+		return
+	}
+	cts, err := contenttypes.AllContentTypes(w)
+	if err != nil {
+		errmessage := fmt.Sprintf("Unable to retrieve content types by %s: %s", w.Request.Host, err.Error())
+		w.Logger.Error(errmessage)
+		services.AddMessage("Unable to retrieve a list of content types.", "Error", w)
+		w.Serve()
+		return
+	}
+	ops := make([]map[string]string, 0)
+	for _, ct := range cts {
+		ops = append(ops, map[string]string{"name": ct.Type, "value": ct.Type})
+	}
+	filters := make([]string, 0)
+	for field, value := range le.Filters {
+		filters = append(filters, field+"="+value)
+	}
+	sort.Strings(filters)
+	f := form.NewForm()
+	f.AddSelect("content_type", ops).AddLabel("Content Type").Required()
+	f.AddTextArea("filters").AddLabel("Filters").AddPlaceHolder("field=value")
+	f.AddText("sort", "text").AddLabel("Sort").AddPlaceHolder("field or -field")
+	f.AddText("page_size", "number").AddLabel("Page Size")
+	f.AddText("item_template", "text").AddLabel("Item Template")
+	f.FormData = map[string]string{
+		"content_type":  le.ContentType,
+		"filters":       strings.Join(filters, "\n"),
+		"sort":          le.Sort,
+		"page_size":     strconv.Itoa(le.PageSize),
+		"item_template": le.ItemTemplate,
+	}
+	f.Register(w)
+	w.SetTemplate("admin/form.html")
+	w.SetPayload("form", f)
+	w.Serve()
+	return
+}
+
+// Controller to save the list query
+func ListEditorSubmit(w *wrapper.Wrapper) {
+	listid := w.APIParams[0]
+	post := make(map[string]interface{})
+	err := form.GetValidFormData(w, &post)
+	if err != nil {
+		return
+	}
+	le, err := elements.LoadListElement(listid, w)
+	if err != nil {
+		errmessage := fmt.Sprintf("Element not found to edit for %s by %s", listid, w.Request.Host)
+		w.Logger.Error(errmessage)
+		services.AddMessage("This element was not found", "Error", w)
+		w.Serve()
+		return
+	}
+	contenttype, _ := post["content_type"].(string)
+	if contenttype == "" {
+		services.AddMessage("A list requires a content type.", "Error", w)
+		w.Serve()
+		return
+	}
+	lv := elements.ListValues{Filters: make(map[string]string)}
+	lv.ContentType = contenttype
+	lv.Sort, _ = post["sort"].(string)
+	lv.ItemTemplate, _ = post["item_template"].(string)
+	lv.PageSize, _ = strconv.Atoi(fmt.Sprint(post["page_size"]))
+	filters, _ := post["filters"].(string)
+	for _, line := range strings.Split(filters, "\n") {
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			continue
+		}
+		lv.Filters[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	le.ListValues = lv
+	_, _, err = le.Query(1, w)
+	if err != nil {
+		services.AddMessage("Invalid list query: "+err.Error(), "Error", w)
+		w.Serve()
+		return
+	}
+	err = le.Save(w)
+	if err != nil {
+		errmessage := fmt.Sprintf("Unable to save list element %s by %s : %s", listid, w.Request.Host, err.Error())
+		w.Logger.Error(errmessage)
+		services.AddMessage("Unable to save the list.", "Error", w)
+		w.Serve()
+		return
+	}
+	services.AddMessage("Your list has been updated.", "Success", w)
+	w.Serve()
+	return
+}
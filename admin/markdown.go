@@ -0,0 +1,76 @@
+package admin
+
+import (
+	"fmt"
+	"github.com/mongolar/mongolar/form"
+	"github.com/mongolar/mongolar/models/elements"
+	"github.com/mongolar/mongolar/services"
+	"github.com/mongolar/mongolar/wrapper"
+)
+
+// Controller to edit a markdown element
+func MarkdownEditor(w *wrapper.Wrapper) {
+	if len(w.APIParams) == 0 {
+		w.Error(403, "Forbidden")
+		return
+	}
+	if w.Request.Method != "POST" {
+		MarkdownEditorForm(w)
+		return
+	}
+	MarkdownEditorSubmit(w)
+	return
+}
+
+// Controller to present the markdown source in a form with the rendered html as a preview
+func MarkdownEditorForm(w *wrapper.Wrapper) {
+	markdownid := w.APIParams[0]
+	e, err := elements.LoadMarkdownElement(markdownid, w)
+	if err != nil {
+		errmessage := fmt.Sprintf("Element not found to edit for %s by %s", markdownid, w.Request.Host)
+		w.Logger.Error(errmessage)
+		services.AddMessage("This element was not found", "Error", w)
+		w.Serve()
+		return
+	}
+	f := form.NewForm()
+	f.AddTextArea("source").AddLabel("Markdown").AddRowsCols(20, 80)
+	f.FormData = map[string]string{"source": e.Source}
+	f.Register(w)
+	w.SetTemplate("admin/form.html")
+	w.SetPayload("form", f)
+	w.SetPayload("html", e.Html)
+	w.Serve()
+	return
+}
+
+// Controller to save the markdown source and render it
+func MarkdownEditorSubmit(w *wrapper.Wrapper) {
+	markdownid := w.APIParams[0]
+	post := make(map[string]string)
+	err := form.GetValidFormData(w, &post)
+	if err != nil {
+		return
+	}
+	e, err := elements.LoadMarkdownElement(markdownid, w)
+	if err != nil {
+		errmessage := fmt.Sprintf("Element not found to edit for %s by %s", markdownid, w.Request.Host)
+		w.Logger.Error(errmessage)
+		services.AddMessage("This element was not found", "Error", w)
+		w.Serve()
+		return
+	}
+	e.Source = post["source"]
+	err = e.Save(w)
+	if err != nil {
+		errmessage := fmt.Sprintf("Unable to save markdown element %s by %s : %s", markdownid, w.Request.Host, err.Error())
+		w.Logger.Error(errmessage)
+		services.AddMessage("Unable to save the markdown.", "Error", w)
+		w.Serve()
+		return
+	}
+	w.SetPayload("html", e.Html)
+	services.AddMessage("Your markdown has been updated.", "Success", w)
+	w.Serve()
+	return
+}
@@ -0,0 +1,205 @@
+package admin
+
+import (
+	"fmt"
+	"github.com/mongolar/mongolar/form"
+	"github.com/mongolar/mongolar/listing"
+	"github.com/mongolar/mongolar/media"
+	"github.com/mongolar/mongolar/models/elements"
+	"github.com/mongolar/mongolar/services"
+	"github.com/mongolar/mongolar/wrapper"
+	"io/ioutil"
+	"net/http"
+	"sort"
+)
+
+// Controller to list the media library
+func MediaLibrary(w *wrapper.Wrapper) {
+	ml, p, err := media.MediaPage(listing.New(w, media.Listing), w)
+	if err != nil {
+		errmessage := fmt.Sprintf("Unable to retrieve the media library: %s", err.Error())
+		w.Logger.Error(errmessage)
+		services.AddMessage("There was a problem retrieving the media library.", "Error", w)
+		w.Serve()
+		return
+	}
+	urls := make(map[string]string)
+	for _, m := range ml {
+		urls[m.MongoId.Hex()] = m.URL("thumbnail", w)
+	}
+	w.SetPayload("media", ml)
+	w.SetPayload("thumbnails", urls)
+	w.SetPayload("page", p)
+	w.Serve()
+	return
+}
+
+// Controller to upload a file posted as multipart form data in the "file"
+// field, with optional "title" and "alt" fields.
+func UploadMedia(w *wrapper.Wrapper) {
+	if w.Request.Method != "POST" {
+		w.Error(403, "Forbidden")
+		return
+	}
+	w.Request.Body = http.MaxBytesReader(w.Writer, w.Request.Body, media.MaxUpload+1<<20)
+	f, h, err := w.Request.FormFile("file")
+	if err != nil {
+		errmessage := fmt.Sprintf("Unable to read upload by %s: %s", w.Request.Host, err.Error())
+		w.Logger.Error(errmessage)
+		services.AddMessage("Unable to read your upload.", "Error", w)
+		w.Serve()
+		return
+	}
+	defer f.Close()
+	b, err := ioutil.ReadAll(f)
+	if err != nil {
+		errmessage := fmt.Sprintf("Unable to read upload by %s: %s", w.Request.Host, err.Error())
+		w.Logger.Error(errmessage)
+		services.AddMessage("Unable to read your upload.", "Error", w)
+		w.Serve()
+		return
+	}
+	m, err := media.Upload(h.Filename, w.Request.FormValue("title"), w.Request.FormValue("alt"), b, w)
+	if err != nil {
+		errmessage := fmt.Sprintf("Unable to save upload %s by %s: %s", h.Filename, w.Request.Host, err.Error())
+		w.Logger.Error(errmessage)
+		services.AddMessage("Unable to save your upload.", "Error", w)
+		w.Serve()
+		return
+	}
+	setMediaDynamic(w)
+	w.SetPayload("media", m)
+	services.AddMessage("Your file has been uploaded.", "Success", w)
+	w.Serve()
+	return
+}
+
+// Controller to delete media and its files
+func DeleteMedia(w *wrapper.Wrapper) {
+	if len(w.APIParams) == 0 {
+		w.Error(403, "Forbidden")
+		return
+	}
+	id := w.APIParams[0]
+	err := media.Delete(id, w)
+	if err != nil {
+		errmessage := fmt.Sprintf("Unable to delete media %s : %s", id, err.Error())
+		w.Logger.Error(errmessage)
+		services.AddMessage("Unable to delete.", "Error", w)
+		w.Serve()
+		return
+	}
+	setMediaDynamic(w)
+	services.AddMessage("Successfully deleted", "Success", w)
+	w.Serve()
+	return
+}
+
+func setMediaDynamic(w *wrapper.Wrapper) {
+	dynamic := services.Dynamic{
+		Target:     "medialist",
+		Controller: "admin/media",
+		Template:   "admin/media_list.html",
+	}
+	services.SetDynamic(dynamic, w)
+}
+
+// Controller to edit an image element
+func ImageEditor(w *wrapper.Wrapper) {
+	if len(w.APIParams) == 0 {
+		w.Error(403, "Forbidden")
+		return
+	}
+	if w.Request.Method != "POST" {
+		ImageEditorForm(w)
+		return
+	}
+	ImageEditorSubmit(w)
+	return
+}
+
+// Controller to present the image element form
+func ImageEditorForm(w *wrapper.Wrapper) {
+	imageid := w.APIParams[0]
+	e, err := elements.LoadImageElement(imageid, w)
+	if err != nil {
+		errmessage := fmt.Sprintf("Element not found to edit for %s by %s", imageid, w.Request.Host)
+		w.Logger.Error(errmessage)
+		services.AddMessage("This element was not found", "Error", w)
+		w.Serve()
+		return
+	}
+	images, err := media.Images(w)
+	if err != nil {
+		errmessage := fmt.Sprintf("Unable to retrieve images by %s: %s", w.Request.Host, err.Error())
+		w.Logger.Error(errmessage)
+		services.AddMessage("There was a problem retrieving the media library.", "Error", w)
+		w.Serve()
+		return
+	}
+	mops := make([]map[string]string, 0)
+	for _, m := range images {
+		mops = append(mops, map[string]string{"name": m.Title, "value": m.MongoId.Hex()})
+	}
+	names := make([]string, 0)
+	for name := range media.Sizes(w.SiteConfig) {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	sops := []map[string]string{map[string]string{"name": "Original", "value": media.Original}}
+	for _, name := range names {
+		sops = append(sops, map[string]string{"name": name, "value": name})
+	}
+	f := form.NewForm()
+	f.AddSelect("media", mops).AddLabel("Image").Required()
+	f.AddSelect("size", sops).AddLabel("Size")
+	f.AddText("alt", "text").AddLabel("Alternative Text")
+	f.AddTextArea("caption").AddLabel("Caption")
+	f.FormData = e.ImageValues
+	f.Register(w)
+	w.SetTemplate("admin/form.html")
+	w.SetPayload("form", f)
+	w.Serve()
+	return
+}
+
+// Controller to save the image element form
+func ImageEditorSubmit(w *wrapper.Wrapper) {
+	imageid := w.APIParams[0]
+	post := make(map[string]string)
+	err := form.GetValidFormData(w, &post)
+	if err != nil {
+		return
+	}
+	e, err := elements.LoadImageElement(imageid, w)
+	if err != nil {
+		errmessage := fmt.Sprintf("Element not found to edit for %s by %s", imageid, w.Request.Host)
+		w.Logger.Error(errmessage)
+		services.AddMessage("This element was not found", "Error", w)
+		w.Serve()
+		return
+	}
+	_, err = media.Load(post["media"], w)
+	if err != nil {
+		services.AddMessage("The image was not found.", "Error", w)
+		w.Serve()
+		return
+	}
+	e.ImageValues = elements.ImageValues{
+		Media:   post["media"],
+		Size:    post["size"],
+		Alt:     post["alt"],
+		Caption: post["caption"],
+	}
+	err = e.Save(w)
+	if err != nil {
+		errmessage := fmt.Sprintf("Unable to save image element %s by %s : %s", imageid, w.Request.Host, err.Error())
+		w.Logger.Error(errmessage)
+		services.AddMessage("Unable to save the image.", "Error", w)
+		w.Serve()
+		return
+	}
+	services.AddMessage("Your image has been updated.", "Success", w)
+	w.Serve()
+	return
+}
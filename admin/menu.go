@@ -6,14 +6,13 @@ import (
 	"github.com/mongolar/mongolar/models/elements"
 	"github.com/mongolar/mongolar/services"
 	"github.com/mongolar/mongolar/wrapper"
-	"net/http"
 )
 
 // Controller to edit a menu element
 // TODO: break this into smaller functions
 func MenuEditor(w *wrapper.Wrapper) {
 	if len(w.APIParams) == 0 {
-		http.Error(w.Writer, "Forbidden", 403)
+		w.Error(403, "Forbidden")
 		return
 	}
 	menuid := w.APIParams[0]
@@ -21,7 +20,7 @@ func MenuEditor(w *wrapper.Wrapper) {
 		e, err := elements.LoadMenuElement(menuid, w)
 		if err != nil {
 			errmessage := fmt.Sprintf("Element not found to edit for %s by %s.", menuid, w.Request.Host)
-			w.SiteConfig.Logger.Error(errmessage)
+			w.Logger.Error(errmessage)
 			services.AddMessage("This element was not found", "Error", w)
 			w.Serve()
 			return
@@ -41,7 +40,7 @@ func MenuEditor(w *wrapper.Wrapper) {
 		e, err := elements.LoadMenuElement(menuid, w)
 		if err != nil {
 			errmessage := fmt.Sprintf("Element not found to edit for %s by %s.", menuid, w.Request.Host)
-			w.SiteConfig.Logger.Error(errmessage)
+			w.Logger.Error(errmessage)
 			services.AddMessage("This element was not found", "Error", w)
 			w.Serve()
 			return
@@ -49,7 +48,7 @@ func MenuEditor(w *wrapper.Wrapper) {
 		err = json.NewDecoder(w.Request.Body).Decode(&e)
 		if err != nil {
 			errmessage := fmt.Sprintf("Unable to update marshall menu elements by %s: %s", w.Request.Host, err.Error())
-			w.SiteConfig.Logger.Error(errmessage)
+			w.Logger.Error(errmessage)
 			services.AddMessage("Unable to save menu element.", "Error", w)
 			w.Serve()
 			return
@@ -57,7 +56,7 @@ func MenuEditor(w *wrapper.Wrapper) {
 		err = e.Save(w)
 		if err != nil {
 			errmessage := fmt.Sprintf("Unable to update menu element %s by %s: %s", menuid, w.Request.Host, err.Error())
-			w.SiteConfig.Logger.Error(errmessage)
+			w.Logger.Error(errmessage)
 			services.AddMessage("Unable to save menu element.", "Error", w)
 			w.Serve()
 			return
@@ -0,0 +1,72 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/mongolar/mongolar/models/tree"
+	"github.com/mongolar/mongolar/services"
+	"github.com/mongolar/mongolar/wrapper"
+)
+
+// Posted structure for moving an element
+type Move struct {
+	Element string      `json:"element"`
+	From    tree.Parent `json:"from"`
+	To      tree.Parent `json:"to"`
+}
+
+// Controller to move an element from one path, wrapper or slug to a position in another.
+func MoveElement(w *wrapper.Wrapper) {
+	if w.Request.Method != "POST" {
+		w.Error(403, "Forbidden")
+		return
+	}
+	var m Move
+	err := json.NewDecoder(w.Request.Body).Decode(&m)
+	if err != nil {
+		errmessage := fmt.Sprintf("Unable to marshall move by %s: %s", w.Request.Host, err.Error())
+		w.Logger.Error(errmessage)
+		services.AddMessage("Unable to move element.", "Error", w)
+		w.Serve()
+		return
+	}
+	err = tree.Move(m.Element, m.From, m.To, w)
+	if err != nil {
+		errmessage := fmt.Sprintf("Unable to move element %s by %s: %s", m.Element, w.Request.Host, err.Error())
+		w.Logger.Error(errmessage)
+		switch err {
+		case tree.ErrCycle, tree.ErrNotChild, tree.ErrChanged, tree.ErrSlugUsed:
+			services.AddMessage(err.Error(), "Error", w)
+		default:
+			services.AddMessage("Unable to move element.", "Error", w)
+		}
+		w.Serve()
+		return
+	}
+	dynamics := []services.Dynamic{parentDynamic(m.From)}
+	if m.From.Id != m.To.Id {
+		dynamics = append(dynamics, parentDynamic(m.To))
+	}
+	services.SetDynamics(dynamics, w)
+	services.AddMessage("The element has been moved.", "Success", w)
+	w.Serve()
+	return
+}
+
+// The dynamic load to refresh a parent in the editor.
+func parentDynamic(p tree.Parent) services.Dynamic {
+	if p.Type == tree.Path {
+		return services.Dynamic{
+			Target:     "centereditor",
+			Controller: "admin/path_elements",
+			Template:   "admin/path_elements.html",
+			Id:         p.Id,
+		}
+	}
+	return services.Dynamic{
+		Target:     p.Id,
+		Controller: "admin/element",
+		Template:   "admin/element.html",
+		Id:         p.Id,
+	}
+}
@@ -5,6 +5,7 @@ package admin
 
 import (
 	"fmt"
+	"github.com/mongolar/mongolar/listing"
 	"github.com/mongolar/mongolar/models/elements"
 	"github.com/mongolar/mongolar/models/paths"
 	"github.com/mongolar/mongolar/services"
@@ -18,61 +19,68 @@ func OrphanElements(w *wrapper.Wrapper) {
 	paths, err := paths.PathList(w)
 	if err != nil {
 		errmessage := fmt.Sprintf("Could not retrieve path elements for orphan list: %s", err.Error())
-		w.SiteConfig.Logger.Error(errmessage)
+		w.Logger.Error(errmessage)
 		services.AddMessage("Could not retrieve path elements.", "Error", w)
 		w.Serve()
+		return
 	}
 	for _, path := range paths {
 		for _, element := range path.Elements {
-			id := bson.ObjectIdHex(element)
-			assigned = append(assigned, id)
+			if bson.IsObjectIdHex(element) {
+				assigned = append(assigned, bson.ObjectIdHex(element))
+			}
 		}
 	}
 	wrappers := make([]elements.WrapperElement, 0)
 	c := w.DbSession.DB("").C("elements")
 	s := bson.M{"controller": "wrapper"}
-	i := c.Find(s).Limit(50).Iter()
+	i := c.Find(s).Iter()
 	err = i.All(&wrappers)
 	if err != nil {
 		errmessage := fmt.Sprintf("Could not retrieve wrapper elements for orphan list: %s", err.Error())
-		w.SiteConfig.Logger.Error(errmessage)
+		w.Logger.Error(errmessage)
 		services.AddMessage("Could not retrieve wrapper elements.", "Error", w)
 		w.Serve()
+		return
 	}
 	for _, wrapper := range wrappers {
 		for _, eid := range wrapper.Elements {
-			bsonid := bson.ObjectIdHex(eid)
-			assigned = append(assigned, bsonid)
+			if bson.IsObjectIdHex(eid) {
+				assigned = append(assigned, bson.ObjectIdHex(eid))
+			}
 		}
 	}
 	slugs := make([]elements.SlugElement, 0)
 	s = bson.M{"controller": "slug"}
-	i = c.Find(s).Limit(50).Iter()
+	i = c.Find(s).Iter()
 	err = i.All(&slugs)
 	if err != nil {
 		errmessage := fmt.Sprintf("Could not retrieve slug elements for orphan list: %s", err.Error())
-		w.SiteConfig.Logger.Error(errmessage)
+		w.Logger.Error(errmessage)
 		services.AddMessage("Could not retrieve slug elements.", "Error", w)
 		w.Serve()
+		return
 	}
 	for _, slug := range slugs {
 		for _, eid := range slug.Slugs {
-			bsonid := bson.ObjectIdHex(eid)
-			assigned = append(assigned, bsonid)
+			if bson.IsObjectIdHex(eid) {
+				assigned = append(assigned, bson.ObjectIdHex(eid))
+			}
 		}
 	}
-	unassigned := new([]elements.Element)
-	s = bson.M{"_id": bson.M{"$nin": assigned}}
-	i = c.Find(s).Limit(50).Iter()
-	err = i.All(unassigned)
+	q := listing.New(w, elements.Listing)
+	q.Filter["_id"] = bson.M{"$nin": assigned}
+	unassigned, p, err := elements.ElementPage(q, w)
 	if err != nil {
 		errmessage := fmt.Sprintf("Could not retrieve unassigned elements: %s", err.Error())
-		w.SiteConfig.Logger.Error(errmessage)
+		w.Logger.Error(errmessage)
 		services.AddMessage("Could not retrieve unassigned elements.", "Error", w)
 		w.Serve()
+		return
 	}
 	w.SetTemplate("admin/orphan_path_elements.html")
 	w.SetPayload("elements", unassigned)
+	w.SetPayload("page", p)
 	w.Serve()
 	return
 }
@@ -3,21 +3,23 @@ package admin
 import (
 	"fmt"
 	"github.com/mongolar/mongolar/form"
+	"github.com/mongolar/mongolar/listing"
 	"github.com/mongolar/mongolar/models/paths"
+	"github.com/mongolar/mongolar/models/redirects"
 	"github.com/mongolar/mongolar/services"
 	"github.com/mongolar/mongolar/wrapper"
-	"net/http"
 )
 
 // Controller to list all paths
 func AdminPaths(w *wrapper.Wrapper) {
-	pl, err := paths.PathList(w)
+	pl, p, err := paths.PathPage(listing.New(w, paths.Listing), w)
 	if err != nil {
 		services.AddMessage("There was an error retrieving your site paths", "Error", w)
 		errmessage := fmt.Sprintf("Error getting path list: %s", err.Error())
-		w.SiteConfig.Logger.Error(errmessage)
+		w.Logger.Error(errmessage)
 	} else {
 		w.SetContent(pl)
+		w.SetPayload("page", p)
 	}
 	w.Serve()
 }
@@ -25,8 +27,7 @@ func AdminPaths(w *wrapper.Wrapper) {
 // Controller for editing paths
 func PathEditor(w *wrapper.Wrapper) {
 	if len(w.APIParams) == 0 {
-		http.Error(w.Writer, "Forbidden", 403)
-		w.Serve()
+		w.Error(403, "Forbidden")
 		return
 	}
 	if w.Request.Method != "POST" {
@@ -57,7 +58,7 @@ func PathEditorForm(w *wrapper.Wrapper) {
 		p, err = paths.LoadPath(pathid, w)
 		if err != nil {
 			errmessage := fmt.Sprintf("Could not retrieve path %s by %s: %s", w.APIParams[0], w.Request.Host, err.Error())
-			w.SiteConfig.Logger.Error(errmessage)
+			w.Logger.Error(errmessage)
 			services.AddMessage("Error retrieving path information.", "Error", w)
 			w.Serve()
 			return
@@ -83,12 +84,13 @@ func PathEditorSubmit(w *wrapper.Wrapper) {
 		if err != nil {
 			errmessage := fmt.Sprintf("Unable to save path %s by %s: %s", pathid,
 				w.Request.Host, err.Error())
-			w.SiteConfig.Logger.Error(errmessage)
+			w.Logger.Error(errmessage)
 			services.AddMessage("There was a problem saving your path.", "Error", w)
 			w.Serve()
 			return
 		}
 	}
+	old := path.Path
 	err = form.GetValidFormData(w, &path)
 	if err != nil {
 		return
@@ -97,11 +99,20 @@ func PathEditorSubmit(w *wrapper.Wrapper) {
 	if err != nil {
 		errmessage := fmt.Sprintf("Unable to save path %s by %s: %s", pathid,
 			w.Request.Host, err.Error())
-		w.SiteConfig.Logger.Error(errmessage)
+		w.Logger.Error(errmessage)
 		services.AddMessage("There was a problem saving your path.", "Error", w)
 		w.Serve()
 		return
 	}
+	if old != "" && old != path.Path && !paths.IsPattern(old) && !paths.IsPattern(path.Path) {
+		err = redirects.Renamed(old, path.Path, path.Wildcard, w)
+		if err != nil {
+			errmessage := fmt.Sprintf("Unable to redirect renamed path %s by %s: %s", pathid,
+				w.Request.Host, err.Error())
+			w.Logger.Error(errmessage)
+			services.AddMessage("Your path was saved but the old url could not be redirected.", "Error", w)
+		}
+	}
 	services.AddMessage("Your path was saved.", "Success", w)
 	dynamic := services.Dynamic{
 		Target:     "pathbar",
@@ -117,15 +128,14 @@ func PathEditorSubmit(w *wrapper.Wrapper) {
 // Retrieve a list of elements in a path for content editor.
 func PathElements(w *wrapper.Wrapper) {
 	if len(w.APIParams) == 0 {
-		http.Error(w.Writer, "Forbidden", 403)
-		w.Serve()
+		w.Error(403, "Forbidden")
 		return
 	}
 	pathid := w.APIParams[0]
 	p, err := paths.LoadPath(pathid, w)
 	if err != nil {
 		errmessage := fmt.Sprintf("Path not found to edit for %s by %s ", pathid, w.Request.Host)
-		w.SiteConfig.Logger.Error(errmessage)
+		w.Logger.Error(errmessage)
 		services.AddMessage("This path was not found", "Error", w)
 		w.Serve()
 	} else {
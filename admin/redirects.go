@@ -0,0 +1,171 @@
+package admin
+
+import (
+	"fmt"
+	"github.com/mongolar/mongolar/form"
+	"github.com/mongolar/mongolar/listing"
+	"github.com/mongolar/mongolar/models/redirects"
+	"github.com/mongolar/mongolar/services"
+	"github.com/mongolar/mongolar/wrapper"
+)
+
+// Controller to list redirects
+func Redirects(w *wrapper.Wrapper) {
+	rl, p, err := redirects.RedirectPage(listing.New(w, redirects.Listing), w)
+	if err != nil {
+		errmessage := fmt.Sprintf("Unable to retrieve redirects: %s", err.Error())
+		w.Logger.Error(errmessage)
+		services.AddMessage("There was a problem retrieving the redirects.", "Error", w)
+		w.Serve()
+		return
+	}
+	w.SetPayload("redirects", rl)
+	w.SetPayload("page", p)
+	w.Serve()
+	return
+}
+
+// Controller for editing redirects, the parameter is the id or "new"
+func RedirectEditor(w *wrapper.Wrapper) {
+	if len(w.APIParams) == 0 {
+		w.Error(403, "Forbidden")
+		return
+	}
+	if w.Request.Method != "POST" {
+		RedirectEditorForm(w)
+		return
+	}
+	RedirectEditorSubmit(w)
+	return
+}
+
+// Controller to present the redirect editor form
+func RedirectEditorForm(w *wrapper.Wrapper) {
+	redirectid := w.APIParams[0]
+	r := redirects.NewRedirect()
+	var err error
+	if redirectid != "new" {
+		r, err = redirects.LoadRedirect(redirectid, w)
+		if err != nil {
+			errmessage := fmt.Sprintf("Redirect not found to edit for %s by %s", redirectid, w.Request.Host)
+			w.Logger.Error(errmessage)
+			services.AddMessage("This redirect was not found", "Error", w)
+			w.Serve()
+			return
+		}
+	}
+	status := []map[string]string{
+		map[string]string{"name": "301 Moved Permanently", "value": "301"},
+		map[string]string{"name": "302 Found", "value": "302"},
+	}
+	f := form.NewForm()
+	f.AddText("from", "text").AddLabel("From").Required()
+	f.AddText("to", "text").AddLabel("To").Required()
+	f.AddCheckBox("prefix").AddLabel("Redirect everything below this url")
+	f.AddRadio("status", status).AddLabel("Status").Required()
+	f.FormData = map[string]interface{}{
+		"from":   r.From,
+		"to":     r.To,
+		"prefix": r.Prefix,
+		"status": fmt.Sprint(r.Status),
+	}
+	f.Register(w)
+	w.SetTemplate("admin/form.html")
+	w.SetPayload("form", f)
+	w.Serve()
+	return
+}
+
+// Controller to save a redirect
+func RedirectEditorSubmit(w *wrapper.Wrapper) {
+	redirectid := w.APIParams[0]
+	var post struct {
+		From   string `json:"from"`
+		To     string `json:"to"`
+		Prefix bool   `json:"prefix"`
+		Status string `json:"status"`
+	}
+	err := form.GetValidFormData(w, &post)
+	if err != nil {
+		return
+	}
+	r := redirects.NewRedirect()
+	if redirectid != "new" {
+		r, err = redirects.LoadRedirect(redirectid, w)
+		if err != nil {
+			errmessage := fmt.Sprintf("Redirect not found to edit for %s by %s", redirectid, w.Request.Host)
+			w.Logger.Error(errmessage)
+			services.AddMessage("This redirect was not found", "Error", w)
+			w.Serve()
+			return
+		}
+	}
+	r.From = post.From
+	r.To = post.To
+	r.Prefix = post.Prefix
+	r.Automatic = false
+	fmt.Sscan(post.Status, &r.Status)
+	err = r.Save(w)
+	if err != nil {
+		services.AddMessage("Unable to save the redirect: "+err.Error(), "Error", w)
+		w.Serve()
+		return
+	}
+	setRedirectsDynamic(w)
+	services.AddMessage("Your redirect was saved.", "Success", w)
+	w.Serve()
+	return
+}
+
+// Controller to delete a redirect
+func DeleteRedirect(w *wrapper.Wrapper) {
+	if len(w.APIParams) == 0 {
+		w.Error(403, "Forbidden")
+		return
+	}
+	id := w.APIParams[0]
+	err := redirects.Delete(id, w)
+	if err != nil {
+		errmessage := fmt.Sprintf("Unable to delete redirect %s : %s", id, err.Error())
+		w.Logger.Error(errmessage)
+		services.AddMessage("Unable to delete.", "Error", w)
+		w.Serve()
+		return
+	}
+	setRedirectsDynamic(w)
+	services.AddMessage("Successfully deleted redirect", "Success", w)
+	w.Serve()
+	return
+}
+
+// Controller to import redirects from a posted CSV file with the columns
+// from, to, status and type ("exact" or "prefix").
+func ImportRedirects(w *wrapper.Wrapper) {
+	if w.Request.Method != "POST" {
+		w.Error(403, "Forbidden")
+		return
+	}
+	n, errs := redirects.ImportCSV(w.Request.Body, w)
+	for _, e := range errs {
+		errmessage := fmt.Sprintf("Redirect import by %s: %s", w.Request.Host, e)
+		w.Logger.Error(errmessage)
+	}
+	if len(errs) > 0 {
+		services.AddMessage("Some redirects could not be imported.", "Error", w)
+		w.SetPayload("errors", errs)
+	}
+	message := fmt.Sprintf("Imported %d redirects.", n)
+	services.AddMessage(message, "Success", w)
+	setRedirectsDynamic(w)
+	w.Serve()
+	return
+}
+
+func setRedirectsDynamic(w *wrapper.Wrapper) {
+	dynamic := services.Dynamic{
+		Target:     "redirectlist",
+		Controller: "admin/redirects",
+		Template:   "admin/redirect_list.html",
+	}
+	services.SetDynamic(dynamic, w)
+}
@@ -7,13 +7,11 @@ import (
 	"github.com/mongolar/mongolar/services"
 	"github.com/mongolar/mongolar/wrapper"
 	"gopkg.in/mgo.v2/bson"
-	"net/http"
 )
 
 func SlugUrlEditor(w *wrapper.Wrapper) {
 	if len(w.APIParams) < 1 {
-		http.Error(w.Writer, "Forbidden", 403)
-		w.Serve()
+		w.Error(403, "Forbidden")
 		return
 	}
 	if w.Request.Method != "POST" {
@@ -29,7 +27,7 @@ func SlugUrlEditorForm(w *wrapper.Wrapper) {
 	e, err := elements.LoadSlugElement(slugid, w)
 	if err != nil {
 		errmessage := fmt.Sprintf("Element not found to edit for %s by %s", slugid, w.Request.Host)
-		w.SiteConfig.Logger.Error(errmessage)
+		w.Logger.Error(errmessage)
 		services.AddMessage("Unable to load slug parent", "Error", w)
 		w.Serve()
 		return
@@ -42,7 +40,7 @@ func SlugUrlEditorForm(w *wrapper.Wrapper) {
 		err = elements.GetById(id, &e, w)
 		if err != nil {
 			errmessage := fmt.Sprintf("Content not found %s : %s", id, err.Error())
-			w.SiteConfig.Logger.Error(errmessage)
+			w.Logger.Error(errmessage)
 			services.AddMessage("There was a problem loading some slug elements.", "Error", w)
 			w.Serve()
 			return
@@ -50,6 +48,8 @@ func SlugUrlEditorForm(w *wrapper.Wrapper) {
 		f.AddText(id, "text").AddLabel(e.Title).Required()
 
 	}
+	f.AddText("slug_param", "text").AddLabel("Path parameter (empty for the rest of a wildcard path)")
+	data["slug_param"] = e.Param
 	f.FormData = data
 	f.Register(w)
 	w.SetTemplate("admin/form.html")
@@ -75,16 +75,17 @@ func SlugUrlEditorSubmit(w *wrapper.Wrapper) {
 	e, err = elements.LoadSlugElement(slugid, w)
 	if err != nil {
 		errmessage := fmt.Sprintf("Element not found to edit for %s by %s", slugid, w.Request.Host)
-		w.SiteConfig.Logger.Error(errmessage)
+		w.Logger.Error(errmessage)
 		services.AddMessage("Unable to load parent slug", "Error", w)
 		w.Serve()
 		return
 	}
 	e.Slugs = vals
+	e.Param = post["slug_param"]
 	err = e.Save(w)
 	if err != nil {
 		errmessage := fmt.Sprintf("Slugs not saved %s by %s", w.APIParams[0], w.Request.Host)
-		w.SiteConfig.Logger.Error(errmessage)
+		w.Logger.Error(errmessage)
 		services.AddMessage("Unable to save slug values.", "Error", w)
 		w.Serve()
 		return
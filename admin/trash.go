@@ -0,0 +1,76 @@
+package admin
+
+import (
+	"fmt"
+	"github.com/mongolar/mongolar/listing"
+	"github.com/mongolar/mongolar/models/trash"
+	"github.com/mongolar/mongolar/services"
+	"github.com/mongolar/mongolar/wrapper"
+)
+
+// Controller to list trashed paths and elements
+func Trash(w *wrapper.Wrapper) {
+	il, p, err := trash.TrashPage(listing.New(w, trash.Listing), w)
+	if err != nil {
+		errmessage := fmt.Sprintf("Unable to retrieve the trash: %s", err.Error())
+		w.Logger.Error(errmessage)
+		services.AddMessage("There was a problem retrieving the trash.", "Error", w)
+		w.Serve()
+		return
+	}
+	w.SetPayload("trash", il)
+	w.SetPayload("page", p)
+	w.Serve()
+	return
+}
+
+// Controller to restore a trashed item to its previous positions
+func RestoreTrash(w *wrapper.Wrapper) {
+	if len(w.APIParams) == 0 {
+		w.Error(403, "Forbidden")
+		return
+	}
+	id := w.APIParams[0]
+	err := trash.Restore(id, w)
+	if err != nil {
+		errmessage := fmt.Sprintf("Unable to restore %s : %s", id, err.Error())
+		w.Logger.Error(errmessage)
+		services.AddMessage("Unable to restore.", "Error", w)
+		w.Serve()
+		return
+	}
+	setTrashDynamic(w)
+	services.AddMessage("Successfully restored", "Success", w)
+	w.Serve()
+	return
+}
+
+// Controller to permanently delete a trashed item
+func PurgeTrash(w *wrapper.Wrapper) {
+	if len(w.APIParams) == 0 {
+		w.Error(403, "Forbidden")
+		return
+	}
+	id := w.APIParams[0]
+	err := trash.Purge(id, w)
+	if err != nil {
+		errmessage := fmt.Sprintf("Unable to purge %s : %s", id, err.Error())
+		w.Logger.Error(errmessage)
+		services.AddMessage("Unable to delete permanently.", "Error", w)
+		w.Serve()
+		return
+	}
+	setTrashDynamic(w)
+	services.AddMessage("Successfully deleted permanently", "Success", w)
+	w.Serve()
+	return
+}
+
+func setTrashDynamic(w *wrapper.Wrapper) {
+	dynamic := services.Dynamic{
+		Target:     "trashlist",
+		Controller: "admin/trash",
+		Template:   "admin/trash_list.html",
+	}
+	services.SetDynamic(dynamic, w)
+}
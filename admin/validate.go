@@ -0,0 +1,80 @@
+// The content report validates every content element against its content type
+// so content saved before validation existed can be found and fixed.
+
+package admin
+
+import (
+	"fmt"
+	"github.com/mongolar/mongolar/form"
+	"github.com/mongolar/mongolar/models/contenttypes"
+	"github.com/mongolar/mongolar/models/elements"
+	"github.com/mongolar/mongolar/services"
+	"github.com/mongolar/mongolar/wrapper"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// A single invalid content element in the content report.
+type ContentReportItem struct {
+	Id      string            `json:"mongolarid"`
+	Title   string            `json:"title"`
+	Type    string            `json:"type"`
+	Errors  map[string]string `json:"errors,omitempty"`
+	Unknown []string          `json:"unknown,omitempty"`
+}
+
+// Controller to validate all content elements against their content types.
+func ValidateAllContent(w *wrapper.Wrapper) {
+	report := make([]ContentReportItem, 0)
+	cts := make(map[string]*contenttypes.ContentType)
+	c := w.DbSession.DB("").C("elements")
+	i := c.Find(bson.M{"controller": "content"}).Iter()
+	e := elements.NewContentElement()
+	for i.Next(&e) {
+		item := ContentReportItem{
+			Id:    e.MongoId.Hex(),
+			Title: e.Title,
+			Type:  e.ContentValues.Type,
+		}
+		if e.ContentValues.Type == "" {
+			item.Errors = map[string]string{"type": "No content type set."}
+			report = append(report, item)
+			e = elements.NewContentElement()
+			continue
+		}
+		ct, ok := cts[e.ContentValues.Type]
+		if !ok {
+			lct, err := contenttypes.LoadContentTypeT(e.ContentValues.Type, w)
+			if err == nil {
+				ct = &lct
+			}
+			cts[e.ContentValues.Type] = ct
+		}
+		if ct == nil {
+			item.Errors = map[string]string{"type": "Content type not found."}
+			report = append(report, item)
+			e = elements.NewContentElement()
+			continue
+		}
+		_, item.Errors = ct.Validate(e.ContentValues.Content)
+		item.Unknown = form.UnknownKeys(ct.Form, e.ContentValues.Content)
+		if len(item.Errors) > 0 || len(item.Unknown) > 0 {
+			report = append(report, item)
+		}
+		e = elements.NewContentElement()
+	}
+	err := i.Close()
+	if err != nil {
+		errmessage := fmt.Sprintf("Unable to validate all content: %s", err.Error())
+		w.Logger.Error(errmessage)
+		services.AddMessage("There was a problem validating your content.", "Error", w)
+		w.Serve()
+		return
+	}
+	if len(report) == 0 {
+		services.AddMessage("All content is valid.", "Success", w)
+	}
+	w.SetTemplate("admin/content_report.html")
+	w.SetPayload("content_report", report)
+	w.Serve()
+	return
+}
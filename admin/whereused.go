@@ -0,0 +1,28 @@
+package admin
+
+import (
+	"fmt"
+	"github.com/mongolar/mongolar/models/tree"
+	"github.com/mongolar/mongolar/services"
+	"github.com/mongolar/mongolar/wrapper"
+)
+
+// Controller to list every path and ancestor chain an element is used in.
+func WhereUsed(w *wrapper.Wrapper) {
+	if len(w.APIParams) == 0 {
+		w.Error(403, "Forbidden")
+		return
+	}
+	id := w.APIParams[0]
+	chains, err := tree.WhereUsed(id, w)
+	if err != nil {
+		errmessage := fmt.Sprintf("Unable to find where %s is used by %s: %s", id, w.Request.Host, err.Error())
+		w.Logger.Error(errmessage)
+		services.AddMessage("Unable to find where this element is used.", "Error", w)
+		w.Serve()
+		return
+	}
+	w.SetPayload("where_used", chains)
+	w.Serve()
+	return
+}
@@ -2,6 +2,7 @@ package basecontrollers
 
 import (
 	"github.com/mongolar/mongolar/controller"
+	"github.com/mongolar/mongolar/middleware"
 )
 
 // Base controllers includes all the basic controllers for Mongolar.
@@ -11,12 +12,24 @@ import (
 // Wrapper - Returns child element ids for elemements assigned as wrapper.
 // Slug - Returns a content element for a wildcard path based on the slug value set in thes lug element.
 // Menu - Returns a Menu for an element tagged as menu.
+// Image - Returns the url and dimensions of the image for an image element.
+// MediaFile - Serves uploaded media files and image variants.
+// Markdown - Returns the html rendered from the markdown source of an element.
+// List - Returns a page of content elements matching the query of a list element.
+// Content, wrapper, slug and menu responses are public and cached, so they run without a session.
 
 func GetControllerMap(cm controller.ControllerMap) {
 	cm["domain_public_value"] = DomainPublicValue
-	cm["path"] = PathValues
+	cm["path"] = middleware.NoCache(PathValues)
 	cm["content"] = ContentValues
 	cm["wrapper"] = WrapperValues
 	cm["slug"] = SlugValues
 	cm["menu"] = MenuValues
+	cm["image"] = ImageValues
+	cm["media_file"] = MediaFile
+	cm["markdown"] = MarkdownValues
+	cm["list"] = ListValues
+	for _, name := range []string{"content", "wrapper", "slug", "menu"} {
+		controller.Public[name] = true
+	}
 }
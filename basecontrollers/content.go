@@ -2,10 +2,11 @@ package basecontrollers
 
 import (
 	"fmt"
+	"github.com/mongolar/mongolar/models/contenttypes"
 	"github.com/mongolar/mongolar/models/elements"
 	"github.com/mongolar/mongolar/services"
 	"github.com/mongolar/mongolar/wrapper"
-	"net/http"
+	"time"
 )
 
 // The controller function for Values found directly in the controller values of the element
@@ -14,20 +15,32 @@ func ContentValues(w *wrapper.Wrapper) {
 	if len(w.APIParams) > 0 {
 		contentid = w.APIParams[0]
 	} else {
-		http.Error(w.Writer, "Forbidden", 403)
-		w.Serve()
+		w.Error(403, "Forbidden")
 		return
 
 	}
 	e, err := elements.LoadContentElement(contentid, w)
 	if err != nil {
 		errmessage := fmt.Sprintf("Content not found %s : %s", contentid, err.Error())
-		w.SiteConfig.Logger.Error(errmessage)
+		w.Logger.Error(errmessage)
 		services.AddMessage("There was a problem loading some content on your page.", "Error", w)
 		w.Serve()
 		return
 	}
-	w.SetContent(e.ContentValues.Content)
-	w.Serve()
+	content := e.ContentValues.Content
+	// Expanded content includes other elements so only the ETag is reliable.
+	modtime := e.Updated
+	if w.Request.URL.Query().Get("expand") != "" {
+		modtime = time.Time{}
+		ct, err := contenttypes.LoadContentTypeT(e.ContentValues.Type, w)
+		if err != nil {
+			errmessage := fmt.Sprintf("Unable to find content type %s : %s", e.ContentValues.Type, err.Error())
+			w.Logger.Error(errmessage)
+		} else {
+			content = ct.Expand(content, w)
+		}
+	}
+	w.SetContent(content)
+	w.ServeCached(modtime, elements.MaxAge(e.Element, w.SiteConfig))
 	return
 }
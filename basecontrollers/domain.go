@@ -2,7 +2,6 @@ package basecontrollers
 
 import (
 	"github.com/mongolar/mongolar/wrapper"
-	"net/http"
 )
 
 // The controller function for Values found in the Site Configuration
@@ -11,16 +10,16 @@ func DomainPublicValue(w *wrapper.Wrapper) {
 	if len(w.APIParams) > 0 {
 		valuekey = w.APIParams[0]
 	} else {
-		http.Error(w.Writer, "Forbidden", 403)
-		w.Serve()
+		w.Error(403, "Forbidden")
 		return
 
 	}
-	if value, ok := w.SiteConfig.PublicValues[valuekey]; ok {
-		w.SetPayload("domain_value", value)
-	} else {
-		http.Error(w.Writer, "Forbidden", 403)
+	value, ok := w.SiteConfig.PublicValues[valuekey]
+	if !ok {
+		w.Error(403, "Forbidden")
+		return
 	}
+	w.SetPayload("domain_value", value)
 	w.Serve()
 	return
 }
@@ -0,0 +1,60 @@
+package basecontrollers
+
+import (
+	"fmt"
+	"github.com/mongolar/mongolar/models/elements"
+	"github.com/mongolar/mongolar/services"
+	"github.com/mongolar/mongolar/wrapper"
+	"strconv"
+)
+
+// The controller for list elements, returns a page of content elements matching the list query.
+// The page number is the second parameter or the "Page" header, and defaults to the first page.
+func ListValues(w *wrapper.Wrapper) {
+	var listid string
+	if len(w.APIParams) > 0 {
+		listid = w.APIParams[0]
+	} else {
+		w.Error(403, "Forbidden")
+		return
+	}
+	le, err := elements.LoadListElement(listid, w)
+	if err != nil {
+		errmessage := fmt.Sprintf("List not found %s : %s", listid, err.Error())
+		w.Logger.Error(errmessage)
+		services.AddMessage("There was a problem loading some content on your page.", "Error", w)
+		w.Serve()
+		return
+	}
+	page := w.Request.Header.Get("Page")
+	if len(w.APIParams) > 1 {
+		page = w.APIParams[1]
+	}
+	number, _ := strconv.Atoi(page)
+	ces, p, err := le.Query(number, w)
+	if err != nil {
+		errmessage := fmt.Sprintf("Unable to query list %s : %s", listid, err.Error())
+		w.Logger.Error(errmessage)
+		services.AddMessage("There was a problem loading some content on your page.", "Error", w)
+		w.Serve()
+		return
+	}
+	items := make([]map[string]interface{}, 0)
+	for _, ce := range ces {
+		template := le.ItemTemplate
+		if template == "" {
+			template = ce.Template
+		}
+		item := map[string]interface{}{
+			"mongolarid":       ce.MongoId.Hex(),
+			"title":            ce.Title,
+			"mongolartemplate": template,
+			"content":          ce.Content,
+		}
+		items = append(items, item)
+	}
+	w.SetContent(items)
+	w.SetPayload("page", p)
+	w.Serve()
+	return
+}
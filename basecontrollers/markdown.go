@@ -0,0 +1,34 @@
+package basecontrollers
+
+import (
+	"fmt"
+	"github.com/mongolar/mongolar/models/elements"
+	"github.com/mongolar/mongolar/services"
+	"github.com/mongolar/mongolar/wrapper"
+)
+
+// The controller for markdown elements, returns the html rendered when the element was saved.
+func MarkdownValues(w *wrapper.Wrapper) {
+	var markdownid string
+	if len(w.APIParams) > 0 {
+		markdownid = w.APIParams[0]
+	} else {
+		w.Error(403, "Forbidden")
+		return
+	}
+	e, err := elements.LoadMarkdownElement(markdownid, w)
+	if err != nil {
+		errmessage := fmt.Sprintf("Markdown not found %s : %s", markdownid, err.Error())
+		w.Logger.Error(errmessage)
+		services.AddMessage("There was a problem loading some content on your page.", "Error", w)
+		w.Serve()
+		return
+	}
+	// Elements saved outside the editor, such as imports, may not be rendered yet.
+	if e.Html == "" && e.Source != "" {
+		e.Html = elements.RenderMarkdown(e.Source)
+	}
+	w.SetContent(map[string]string{"html": e.Html})
+	w.Serve()
+	return
+}
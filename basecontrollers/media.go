@@ -0,0 +1,94 @@
+package basecontrollers
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/mongolar/mongolar/media"
+	"github.com/mongolar/mongolar/models/elements"
+	"github.com/mongolar/mongolar/services"
+	"github.com/mongolar/mongolar/wrapper"
+	"net/http"
+)
+
+// The controller for image elements, returns the url and dimensions of the image in the element's size.
+func ImageValues(w *wrapper.Wrapper) {
+	var imageid string
+	if len(w.APIParams) > 0 {
+		imageid = w.APIParams[0]
+	} else {
+		w.Error(403, "Forbidden")
+		return
+	}
+	e, err := elements.LoadImageElement(imageid, w)
+	if err != nil {
+		errmessage := fmt.Sprintf("Image not found %s : %s", imageid, err.Error())
+		w.Logger.Error(errmessage)
+		services.AddMessage("There was a problem loading some content on your page.", "Error", w)
+		w.Serve()
+		return
+	}
+	m, err := media.Load(e.Media, w)
+	if err != nil {
+		errmessage := fmt.Sprintf("Media %s not found for image %s : %s", e.Media, imageid, err.Error())
+		w.Logger.Error(errmessage)
+		services.AddMessage("There was a problem loading some content on your page.", "Error", w)
+		w.Serve()
+		return
+	}
+	size := e.Size
+	if size == "" {
+		size = media.Original
+	}
+	alt := e.Alt
+	if alt == "" {
+		alt = m.Alt
+	}
+	width, height := m.Dimensions(size, w.SiteConfig)
+	content := map[string]interface{}{
+		"url":     m.URL(size, w),
+		"width":   width,
+		"height":  height,
+		"alt":     alt,
+		"title":   m.Title,
+		"caption": e.Caption,
+	}
+	w.SetContent(content)
+	w.Serve()
+	return
+}
+
+// The controller serving media files, the parameters are the media id and
+// the size, which defaults to the original.  Only images are served inline,
+// anything else is a download so uploaded html can not run on the site.
+func MediaFile(w *wrapper.Wrapper) {
+	if len(w.APIParams) == 0 {
+		w.Error(403, "Forbidden")
+		return
+	}
+	size := media.Original
+	if len(w.APIParams) > 1 && w.APIParams[1] != "" {
+		size = w.APIParams[1]
+	}
+	m, err := media.Load(w.APIParams[0], w)
+	if err != nil {
+		http.NotFound(w.Writer, w.Request)
+		w.Close()
+		return
+	}
+	b, contenttype, err := media.File(m, size, w)
+	if err != nil {
+		errmessage := fmt.Sprintf("Unable to serve media %s in size %s : %s", w.APIParams[0], size, err.Error())
+		w.Logger.Error(errmessage)
+		http.NotFound(w.Writer, w.Request)
+		w.Close()
+		return
+	}
+	h := w.Writer.Header()
+	h.Set("X-Content-Type-Options", "nosniff")
+	if !m.IsImage() {
+		h.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", m.Name))
+		h.Set("Content-Security-Policy", "sandbox")
+	}
+	w.ServeContent(m.Name, contenttype, m.Uploaded, bytes.NewReader(b))
+	return
+}
@@ -5,7 +5,6 @@ import (
 	"github.com/mongolar/mongolar/models/elements"
 	"github.com/mongolar/mongolar/services"
 	"github.com/mongolar/mongolar/wrapper"
-	"net/http"
 )
 
 // The controller function for Values found directly in the controller values of the element
@@ -14,20 +13,19 @@ func MenuValues(w *wrapper.Wrapper) {
 	if len(w.APIParams) > 0 {
 		menuid = w.APIParams[0]
 	} else {
-		http.Error(w.Writer, "Forbidden", 403)
-		w.Serve()
+		w.Error(403, "Forbidden")
 		return
 
 	}
 	e, err := elements.LoadMenuElement(menuid, w)
 	if err != nil {
 		errmessage := fmt.Sprintf("Content not found %s : %s", menuid, err.Error())
-		w.SiteConfig.Logger.Error(errmessage)
+		w.Logger.Error(errmessage)
 		services.AddMessage("There was a problem loading some content on your page.", "Error", w)
 		w.Serve()
 		return
 	}
 	w.SetContent(e.MenuItems)
-	w.Serve()
+	w.ServeCached(e.Updated, elements.MaxAge(e.Element, w.SiteConfig))
 	return
 }
@@ -4,28 +4,29 @@ import (
 	"fmt"
 	"github.com/mongolar/mongolar/models/elements"
 	"github.com/mongolar/mongolar/models/paths"
+	"github.com/mongolar/mongolar/models/redirects"
 	"github.com/mongolar/mongolar/services"
 	"github.com/mongolar/mongolar/wrapper"
-	"net/http"
 )
 
 // The controller function to retrieve elements ids from the path
+// Request is never url based so it is registered with NoCache.
 func PathValues(w *wrapper.Wrapper) {
-	// Request is never url based so we can Never cache this request.
-	// TODO: move no caching into a reusable function.
-	w.Writer.Header().Add("Cache-Control", "no-cache, no-store, must-revalidate")
-	w.Writer.Header().Add("Pragma", "no-cache")
-	w.Writer.Header().Add("Expires", "0")
 	p := paths.NewPath()
 	u := w.Request.Header.Get("CurrentPath")
 	if u == "" {
-		http.Error(w.Writer, "Forbidden", 403)
-		w.Serve()
+		w.Error(403, "Forbidden")
 		return
 	}
-	qp, err := p.PathMatch(u, "published", w)
+	_, err := p.PathMatch(u, "published", w)
 	if err != nil {
 		if err.Error() == "not found" {
+			_, to, rerr := redirects.Match(u, w)
+			if rerr == nil {
+				services.Redirect(to, w)
+				w.Serve()
+				return
+			}
 			if "/"+w.SiteConfig.FourOFour != u {
 				services.Redirect("/"+w.SiteConfig.FourOFour, w)
 				w.Serve()
@@ -39,18 +40,16 @@ func PathValues(w *wrapper.Wrapper) {
 		}
 
 	}
-	var v []elements.Element
-	for _, eid := range p.Elements {
-		e := elements.NewElement()
-		err = elements.GetById(eid, &e, w)
-		if err != nil {
-			errmessage := fmt.Sprintf("Content not found %s : %s", eid, err.Error())
-			w.SiteConfig.Logger.Error(errmessage)
-		} else {
-			v = append(v, e)
-		}
+	v, missing, err := elements.GetElements(p.Elements, w)
+	if err != nil {
+		errmessage := fmt.Sprintf("Unable to load elements : %s", err.Error())
+		w.Logger.Error(errmessage)
+	}
+	for _, eid := range missing {
+		errmessage := fmt.Sprintf("Content not found %s", eid)
+		w.Logger.Error(errmessage)
 	}
-	w.SetPayload("mongolar_slug", qp)
+	w.SetPayload("mongolar_params", p.Params)
 	w.SetContent(v)
 	w.SetTemplate(p.Template)
 	w.Serve()
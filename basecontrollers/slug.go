@@ -3,9 +3,9 @@ package basecontrollers
 import (
 	"fmt"
 	"github.com/mongolar/mongolar/models/elements"
+	"github.com/mongolar/mongolar/models/paths"
 	"github.com/mongolar/mongolar/services"
 	"github.com/mongolar/mongolar/wrapper"
-	"net/http"
 )
 
 // The controller function for elements that are context specific
@@ -14,32 +14,39 @@ func SlugValues(w *wrapper.Wrapper) {
 	if len(w.APIParams) > 0 {
 		slugid = w.APIParams[0]
 	} else {
-		http.Error(w.Writer, "Forbidden", 403)
-		w.Serve()
+		w.Error(403, "Forbidden")
 		return
 
 	}
 	es, err := elements.LoadSlugElement(slugid, w)
 	if err != nil {
 		errmessage := fmt.Sprintf("Content not found %s : %s", slugid, err.Error())
-		w.SiteConfig.Logger.Error(errmessage)
+		w.Logger.Error(errmessage)
+		services.AddMessage("There was a problem loading some content on your page.", "Error", w)
+		w.Serve()
+		return
+	}
+	slug, err := slugValue(es, w)
+	if err != nil {
+		errmessage := fmt.Sprintf("Unable to match path %s : %s", w.Request.Header.Get("CurrentPath"), err.Error())
+		w.Logger.Error(errmessage)
 		services.AddMessage("There was a problem loading some content on your page.", "Error", w)
 		w.Serve()
 		return
 	}
-	if _, ok := es.Slugs[w.Request.Header.Get("Slug")]; !ok {
-		errmessage := fmt.Sprintf("Slug content not found for query %s", w.Request.Header.Get("QueryParameter"))
-		w.SiteConfig.Logger.Error(errmessage)
+	id, ok := es.Slugs[slug]
+	if !ok {
+		errmessage := fmt.Sprintf("Slug content not found for query %s", slug)
+		w.Logger.Error(errmessage)
 		services.AddMessage("There was a problem loading some content on your page.", "Error", w)
 		w.Serve()
 		return
 	}
-	id := es.Slugs[w.Request.Header.Get("Slug")]
 	var e elements.ContentElement
 	e, err = elements.LoadContentElement(id, w)
 	if err != nil {
 		errmessage := fmt.Sprintf("Content not found %s : %s", w.APIParams[0], err.Error())
-		w.SiteConfig.Logger.Error(errmessage)
+		w.Logger.Error(errmessage)
 		services.AddMessage("There was a problem loading some content on your page.", "Error", w)
 		w.Serve()
 		return
@@ -47,6 +54,27 @@ func SlugValues(w *wrapper.Wrapper) {
 	w.SetTemplate(e.Template)
 	w.SetDynamicId(e.DynamicId)
 	w.SetContent(e.ContentValues.Content)
-	w.Serve()
+	// The content depends on the url, so caches must key on the headers it is read from.
+	w.Writer.Header().Set("Vary", "CurrentPath, Slug")
+	w.ServeCached(elements.LastModified(es.Element, e.Element), elements.MaxAge(es.Element, w.SiteConfig))
 	return
 }
+
+// The slug is taken from the parameters of the current path, or the Slug
+// header when the current path is not sent.
+func slugValue(es elements.SlugElement, w *wrapper.Wrapper) (string, error) {
+	u := w.Request.Header.Get("CurrentPath")
+	if u == "" {
+		return w.Request.Header.Get("Slug"), nil
+	}
+	p := paths.NewPath()
+	_, err := p.PathMatch(u, "published", w)
+	if err != nil {
+		return "", err
+	}
+	param := es.Param
+	if param == "" {
+		param = paths.Rest
+	}
+	return p.Params[param], nil
+}
@@ -5,7 +5,6 @@ import (
 	"github.com/mongolar/mongolar/models/elements"
 	"github.com/mongolar/mongolar/services"
 	"github.com/mongolar/mongolar/wrapper"
-	"net/http"
 )
 
 // The controller function for Values found directly in the controller values of the element
@@ -14,31 +13,33 @@ func WrapperValues(w *wrapper.Wrapper) {
 	if len(w.APIParams) > 0 {
 		wrapid = w.APIParams[0]
 	} else {
-		http.Error(w.Writer, "Forbidden", 403)
-		w.Serve()
+		w.Error(403, "Forbidden")
 		return
 	}
 	e, err := elements.LoadWrapperElement(wrapid, w)
 	if err != nil {
 		errmessage := fmt.Sprintf("Content not found %s : %s", wrapid, err.Error())
-		w.SiteConfig.Logger.Error(errmessage)
+		w.Logger.Error(errmessage)
 		services.AddMessage("There was a problem loading some content on your page.", "Error", w)
 		w.Serve()
 		return
 	}
-	var v []elements.Element
-	for _, id := range e.Elements {
-		e := elements.NewElement()
-		err = elements.GetById(id, &e, w)
-		if err != nil {
-			errmessage := fmt.Sprintf("Content not found %s : %s", id, err.Error())
-			w.SiteConfig.Logger.Error(errmessage)
-		} else {
-			v = append(v, e)
-		}
+	v, missing, err := elements.GetElements(e.Elements, w)
+	if err != nil {
+		errmessage := fmt.Sprintf("Unable to load elements : %s", err.Error())
+		w.Logger.Error(errmessage)
+	}
+	for _, eid := range missing {
+		errmessage := fmt.Sprintf("Content not found %s", eid)
+		w.Logger.Error(errmessage)
 	}
 	w.SetClasses(e.Classes)
 	w.SetDynamicId(e.DynamicId)
 	w.SetContent(v)
-	w.Serve()
+	modtime := elements.LastModified(append(v, e.Element)...)
+	if len(missing) > 0 || err != nil {
+		w.Serve()
+		return
+	}
+	w.ServeCached(modtime, elements.MaxAge(e.Element, w.SiteConfig))
 }
@@ -0,0 +1,391 @@
+// Bundles are portable archives of a site's paths, elements and content types.
+// They are used to clone a site or move it between environments.
+// A bundle is a zip archive holding a manifest.json and one JSON file per
+// collection with the documents as they are stored in MongoDB.  Documents are
+// written as MongoDB extended JSON so ids and dates keep their types.
+
+package bundle
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/mongolar/mongolar/form"
+	"github.com/mongolar/mongolar/models/contenttypes"
+	"github.com/mongolar/mongolar/models/elements"
+	"github.com/mongolar/mongolar/models/paths"
+	"github.com/mongolar/mongolar/models/references"
+	"github.com/mongolar/mongolar/wrapper"
+	"gopkg.in/mgo.v2/bson"
+	"io"
+	"io/ioutil"
+	"math"
+	"time"
+)
+
+// The bundle format version, imports of newer versions are refused.
+// Version 1 bundles hold plain JSON, which is read the same way.
+const Version = 2
+
+// Id options for import
+// 	preserve: Documents keep their ids and replace documents with the same id
+// 	remap: Documents get new ids and all references to them are rewritten,
+//		so a bundle can be imported into a site more than once
+const (
+	IdsPreserve = "preserve"
+	IdsRemap    = "remap"
+)
+
+// Collections in a bundle, in the order they are imported.
+var Collections = []string{"content_types", "elements", "paths"}
+
+// Describes the contents of a bundle
+type Manifest struct {
+	Version     int            `json:"version"`
+	Created     time.Time      `json:"created"`
+	Collections map[string]int `json:"collections"`
+}
+
+// The result of an import
+type Report struct {
+	Manifest Manifest          `json:"manifest"`
+	Imported map[string]int    `json:"imported"`
+	Errors   []string          `json:"errors,omitempty"`
+	Ids      map[string]string `json:"ids,omitempty"`
+}
+
+// Write a bundle of the site to out.
+func Export(out io.Writer, w *wrapper.Wrapper) (Manifest, error) {
+	m := Manifest{
+		Version:     Version,
+		Created:     time.Now(),
+		Collections: make(map[string]int),
+	}
+	zw := zip.NewWriter(out)
+	for _, name := range Collections {
+		docs := make([]bson.M, 0)
+		err := w.DbSession.DB("").C(name).Find(nil).All(&docs)
+		if err != nil {
+			return m, err
+		}
+		err = writeDocs(zw, name+".json", docs)
+		if err != nil {
+			return m, err
+		}
+		m.Collections[name] = len(docs)
+	}
+	err := writeJSON(zw, "manifest.json", m)
+	if err != nil {
+		return m, err
+	}
+	return m, zw.Close()
+}
+
+// Import a bundle into the site, ids is one of the id options.
+// Documents that fail to import are listed in the report errors.
+func Import(b []byte, ids string, w *wrapper.Wrapper) (Report, error) {
+	r := Report{Imported: make(map[string]int)}
+	if ids != IdsPreserve && ids != IdsRemap {
+		return r, fmt.Errorf("Unknown id option %s", ids)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		return r, err
+	}
+	docs := make(map[string][]bson.M)
+	for _, f := range zr.File {
+		switch f.Name {
+		case "manifest.json":
+			err = readJSON(f, &r.Manifest)
+		case "content_types.json", "elements.json", "paths.json":
+			var d []bson.M
+			d, err = readDocs(f)
+			docs[f.Name[:len(f.Name)-len(".json")]] = d
+		}
+		if err != nil {
+			return r, err
+		}
+	}
+	if r.Manifest.Version < 1 || r.Manifest.Version > Version {
+		return r, errors.New("Unsupported bundle version")
+	}
+	i := newImporter(ids, docs)
+	if ids == IdsRemap {
+		r.Ids = i.ids
+	}
+	i.importContentTypes(&r, w)
+	i.importElements(&r, w)
+	i.importPaths(&r, w)
+	return r, nil
+}
+
+type importer struct {
+	mode  string
+	docs  map[string][]bson.M
+	ids   map[string]string
+	forms map[string][]*form.Field
+}
+
+func newImporter(mode string, docs map[string][]bson.M) *importer {
+	i := &importer{
+		mode:  mode,
+		docs:  docs,
+		ids:   make(map[string]string),
+		forms: make(map[string][]*form.Field),
+	}
+	for _, name := range Collections {
+		for _, d := range docs[name] {
+			id := hex(d["_id"])
+			if id == "" {
+				continue
+			}
+			if mode == IdsRemap {
+				i.ids[id] = bson.NewObjectId().Hex()
+			} else {
+				i.ids[id] = id
+			}
+		}
+	}
+	for _, d := range docs["content_types"] {
+		t, _ := d["type"].(string)
+		i.forms[t] = decodeForm(d["form"])
+	}
+	return i
+}
+
+// Content types are matched by type.
+func (i *importer) importContentTypes(r *Report, w *wrapper.Wrapper) {
+	c := w.DbSession.DB("").C("content_types")
+	for _, d := range i.docs["content_types"] {
+		id := i.id(d["_id"])
+		delete(d, "_id")
+		_, err := c.Upsert(bson.M{"type": d["type"]}, bson.M{"$set": d, "$setOnInsert": bson.M{"_id": id}})
+		i.result(r, "content_types", id, err)
+	}
+}
+
+func (i *importer) importElements(r *Report, w *wrapper.Wrapper) {
+	c := w.DbSession.DB("").C("elements")
+	for _, d := range i.docs["elements"] {
+		id := i.id(d["_id"])
+		d["_id"] = id
+		cv, _ := d["controller_values"].(map[string]interface{})
+		switch d["controller"] {
+		case "wrapper":
+			if cv != nil {
+				cv["elements"] = i.remapList(cv["elements"])
+			}
+		case "slug":
+			for slug, eid := range cv {
+				if s, ok := eid.(string); ok {
+					cv[slug] = i.remap(s)
+				}
+			}
+		case "content":
+			err := i.remapContent(id.Hex(), cv, w)
+			if err != nil {
+				r.Errors = append(r.Errors, fmt.Sprintf("references %s: %s", id.Hex(), err.Error()))
+			}
+		}
+		d["updated"] = time.Now()
+		_, err := c.Upsert(bson.M{"_id": id}, d)
+		i.result(r, "elements", id, err)
+	}
+	elements.InvalidateAll(w)
+}
+
+func (i *importer) importPaths(r *Report, w *wrapper.Wrapper) {
+	c := w.DbSession.DB("").C("paths")
+	for _, d := range i.docs["paths"] {
+		id := i.id(d["_id"])
+		d["_id"] = id
+		if _, ok := d["elements"]; ok {
+			d["elements"] = i.remapList(d["elements"])
+		}
+		if u, ok := d["path"].(string); ok {
+			d["pattern"] = paths.IsPattern(u)
+			d["segments"] = paths.SegmentCount(u)
+		}
+		_, err := c.Upsert(bson.M{"_id": id}, d)
+		i.result(r, "paths", id, err)
+	}
+	paths.Invalidate(w.SiteConfig)
+}
+
+// Rewrite reference fields and rebuild the reference index for a content element.
+func (i *importer) remapContent(id string, cv map[string]interface{}, w *wrapper.Wrapper) error {
+	if cv == nil {
+		return nil
+	}
+	t, _ := cv["type"].(string)
+	content, ok := cv["content"].(map[string]interface{})
+	fields, fok := i.forms[t]
+	if !ok || !fok {
+		return nil
+	}
+	content = form.MapReferences(fields, content, func(p string, f *form.Field, ids []string) interface{} {
+		remapped := make([]string, 0)
+		for _, rid := range ids {
+			remapped = append(remapped, i.remap(rid))
+		}
+		if f.TemplateOptions != nil && f.TemplateOptions.Multiple {
+			return remapped
+		}
+		if len(remapped) == 0 {
+			return nil
+		}
+		return remapped[0]
+	})
+	cv["content"] = content
+	ct := contenttypes.ContentType{Form: fields}
+	return references.Set(id, ct.References(content), w)
+}
+
+func (i *importer) result(r *Report, collection string, id bson.ObjectId, err error) {
+	if err != nil {
+		r.Errors = append(r.Errors, fmt.Sprintf("%s %s: %s", collection, id.Hex(), err.Error()))
+		return
+	}
+	r.Imported[collection]++
+}
+
+// The id a document is imported with.
+func (i *importer) id(v interface{}) bson.ObjectId {
+	if id, ok := i.ids[hex(v)]; ok {
+		return bson.ObjectIdHex(id)
+	}
+	return bson.NewObjectId()
+}
+
+// Ids that are not in the bundle are left as they are.
+func (i *importer) remap(id string) string {
+	if nid, ok := i.ids[id]; ok {
+		return nid
+	}
+	return id
+}
+
+func (i *importer) remapList(v interface{}) []string {
+	l := make([]string, 0)
+	items, _ := v.([]interface{})
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			l = append(l, i.remap(s))
+		}
+	}
+	return l
+}
+
+// Ids are exported as hex strings.
+func hex(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		if bson.IsObjectIdHex(t) {
+			return t
+		}
+	case bson.ObjectId:
+		return t.Hex()
+	}
+	return ""
+}
+
+// Decode the stored form of a content type.
+func decodeForm(v interface{}) []*form.Field {
+	var ct struct {
+		Form []*form.Field `bson:"form"`
+	}
+	b, err := bson.Marshal(bson.M{"form": v})
+	if err == nil {
+		bson.Unmarshal(b, &ct)
+	}
+	return ct.Form
+}
+
+func writeJSON(zw *zip.Writer, name string, v interface{}) error {
+	js, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(zw, name, js)
+}
+
+// Documents are written as extended JSON, indented like the manifest.
+func writeDocs(zw *zip.Writer, name string, docs []bson.M) error {
+	ext, err := bson.MarshalJSON(docs)
+	if err != nil {
+		return err
+	}
+	var js bytes.Buffer
+	err = json.Indent(&js, ext, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(zw, name, js.Bytes())
+}
+
+func writeFile(zw *zip.Writer, name string, b []byte) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(b)
+	return err
+}
+
+func readFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return ioutil.ReadAll(rc)
+}
+
+func readJSON(f *zip.File, v interface{}) error {
+	b, err := readFile(f)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// Numbers are decoded as integers where possible so they are stored as they were exported.
+func readDocs(f *zip.File) ([]bson.M, error) {
+	b, err := readFile(f)
+	if err != nil {
+		return nil, err
+	}
+	var docs []bson.M
+	err = bson.UnmarshalJSON(b, &docs)
+	if err != nil {
+		return nil, err
+	}
+	for _, doc := range docs {
+		numbers(doc)
+	}
+	return docs, nil
+}
+
+func numbers(v interface{}) interface{} {
+	switch t := v.(type) {
+	case float64:
+		if t == math.Trunc(t) && math.Abs(t) < 1<<53 {
+			return int64(t)
+		}
+	case bson.M:
+		for k, i := range t {
+			t[k] = numbers(i)
+		}
+	case map[string]interface{}:
+		for k, i := range t {
+			t[k] = numbers(i)
+		}
+	case []interface{}:
+		for k, i := range t {
+			t[k] = numbers(i)
+		}
+	}
+	return v
+}
@@ -0,0 +1,93 @@
+package bundle
+
+import (
+	"archive/zip"
+	"bytes"
+	"gopkg.in/mgo.v2/bson"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDocsRoundTrip(t *testing.T) {
+	id := bson.NewObjectId()
+	ref := bson.NewObjectId()
+	// Dates are kept to the millisecond and decoded in UTC.
+	updated := time.Date(2016, 3, 1, 12, 30, 0, 5e6, time.UTC)
+	docs := []bson.M{
+		bson.M{
+			"_id":        id,
+			"controller": "content",
+			"updated":    updated,
+			"weight":     3,
+			"ratio":      1.5,
+			"controller_values": bson.M{
+				"parent":   ref,
+				"elements": []interface{}{"a", "b"},
+				"created":  updated,
+			},
+		},
+	}
+	var b bytes.Buffer
+	zw := zip.NewWriter(&b)
+	err := writeDocs(zw, "elements.json", docs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = zw.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	read, err := readDocs(zr.File[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []bson.M{
+		bson.M{
+			"_id":        id,
+			"controller": "content",
+			"updated":    updated,
+			"weight":     int64(3),
+			"ratio":      1.5,
+			"controller_values": map[string]interface{}{
+				"parent":   ref,
+				"elements": []interface{}{"a", "b"},
+				"created":  updated,
+			},
+		},
+	}
+	if !reflect.DeepEqual(read, expected) {
+		t.Errorf("read %#v, expected %#v", read, expected)
+	}
+}
+
+func TestReadPlainDocs(t *testing.T) {
+	var b bytes.Buffer
+	zw := zip.NewWriter(&b)
+	err := writeFile(zw, "paths.json", []byte(`[{"_id": "56d5a1b2c3d4e5f6a7b8c9d0", "path": "/", "elements": ["a"], "weight": 2}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw.Close()
+	zr, err := zip.NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	read, err := readDocs(zr.File[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []bson.M{
+		bson.M{"_id": "56d5a1b2c3d4e5f6a7b8c9d0", "path": "/", "elements": []interface{}{"a"}, "weight": int64(2)},
+	}
+	if !reflect.DeepEqual(read, expected) {
+		t.Errorf("read %#v, expected %#v", read, expected)
+	}
+	if hex(read[0]["_id"]) != "56d5a1b2c3d4e5f6a7b8c9d0" {
+		t.Errorf("version 1 ids are not read")
+	}
+}
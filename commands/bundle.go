@@ -0,0 +1,45 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"github.com/mongolar/mongolar/bundle"
+	"github.com/mongolar/mongolar/wrapper"
+	"io/ioutil"
+)
+
+// Export a bundle of the site to a zip file.
+//	mongolar export_site my_site site.zip
+func ExportSite(w *wrapper.Wrapper, args []string) error {
+	if len(args) < 1 {
+		return errors.New("Usage: export_site <site> <file.zip>")
+	}
+	var b bytes.Buffer
+	_, err := bundle.Export(&b, w)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(args[0], b.Bytes(), 0644)
+}
+
+// Import a bundle into the site, ids are either preserved or remapped.
+// The import report is printed as JSON.
+//	mongolar import_site my_other_site site.zip remap
+func ImportSite(w *wrapper.Wrapper, args []string) error {
+	if len(args) < 1 {
+		return errors.New("Usage: import_site <site> <file.zip> [preserve|remap]")
+	}
+	ids := bundle.IdsRemap
+	if len(args) > 1 {
+		ids = args[1]
+	}
+	b, err := ioutil.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	r, err := bundle.Import(b, ids, w)
+	if err != nil {
+		return err
+	}
+	return printJSON(r)
+}
@@ -0,0 +1,63 @@
+// Commands run administrative tasks against a site from the command line
+// instead of serving it.  The first argument is the command, the second the
+// site configuration name and the rest are passed to the command.
+//	mongolar export_content_types my_site /tmp/content_types.yaml
+
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mongolar/mongolar/configs"
+	"github.com/mongolar/mongolar/wrapper"
+	"sort"
+	"strings"
+)
+
+// A command is any function that takes a wrapper for the site and its arguments.
+type Command func(*wrapper.Wrapper, []string) error
+
+// The map structure for Commands
+type CommandMap map[string]Command
+
+// Creates a map for commands
+func NewMap() CommandMap {
+	return make(CommandMap)
+}
+
+// Package function to add the built in commands to a command map.
+func GetCommandMap(cm CommandMap) {
+	cm["export_content_types"] = ExportContentTypes
+	cm["import_content_types"] = ImportContentTypes
+	cm["export_site"] = ExportSite
+	cm["import_site"] = ImportSite
+	cm["check_integrity"] = CheckIntegrity
+	cm["import_redirects"] = ImportRedirects
+}
+
+// Run the command named by the first argument against the site named by the second.
+func (cm CommandMap) Run(sm configs.SitesMap, args []string) error {
+	if len(args) < 2 {
+		return cm.usage()
+	}
+	c, ok := cm[args[0]]
+	if !ok {
+		return cm.usage()
+	}
+	s, ok := sm[args[1]]
+	if !ok {
+		return fmt.Errorf("No site configuration named %s", args[1])
+	}
+	w := wrapper.NewBackground(s)
+	defer w.Close()
+	return c(w, args[2:])
+}
+
+func (cm CommandMap) usage() error {
+	names := make([]string, 0)
+	for n := range cm {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return errors.New("Usage: mongolar <command> <site> [arguments]\nCommands: " + strings.Join(names, ", "))
+}
@@ -0,0 +1,61 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/mongolar/mongolar/models/contenttypes"
+	"github.com/mongolar/mongolar/wrapper"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// Export all content types to a file, the format is taken from the extension.
+//	mongolar export_content_types my_site content_types.yaml
+func ExportContentTypes(w *wrapper.Wrapper, args []string) error {
+	if len(args) < 1 {
+		return errors.New("Usage: export_content_types <site> <file.yaml|file.json>")
+	}
+	b, err := contenttypes.ExportContentTypes(format(args[0]), w)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(args[0], b, 0644)
+}
+
+// Import content types from a file, the mode is diff, merge or replace.
+// The changes are printed as JSON.
+//	mongolar import_content_types my_site content_types.yaml merge
+func ImportContentTypes(w *wrapper.Wrapper, args []string) error {
+	if len(args) < 1 {
+		return errors.New("Usage: import_content_types <site> <file.yaml|file.json> [diff|merge|replace]")
+	}
+	mode := contenttypes.ImportDiff
+	if len(args) > 1 {
+		mode = args[1]
+	}
+	b, err := ioutil.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	changes, err := contenttypes.ImportContentTypes(b, format(args[0]), mode, w)
+	if err != nil {
+		return err
+	}
+	return printJSON(changes)
+}
+
+// The file format based on the file extension.
+func format(f string) string {
+	return strings.TrimPrefix(filepath.Ext(f), ".")
+}
+
+func printJSON(v interface{}) error {
+	js, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(js))
+	return nil
+}
@@ -0,0 +1,23 @@
+package commands
+
+import (
+	"github.com/mongolar/mongolar/integrity"
+	"github.com/mongolar/mongolar/wrapper"
+)
+
+// Check the integrity of the site and print the report as JSON.
+// Passing "repair" repairs the problems that can be repaired.
+//	mongolar check_integrity my_site repair
+func CheckIntegrity(w *wrapper.Wrapper, args []string) error {
+	var r integrity.Report
+	var err error
+	if len(args) > 0 && args[0] == "repair" {
+		r, err = integrity.Repair(w)
+	} else {
+		r, err = integrity.Check(w)
+	}
+	if err != nil {
+		return err
+	}
+	return printJSON(r)
+}
@@ -0,0 +1,31 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mongolar/mongolar/models/redirects"
+	"github.com/mongolar/mongolar/wrapper"
+	"os"
+)
+
+// Import redirects from a CSV file with the columns from, to, status and type.
+//	mongolar import_redirects my_site redirects.csv
+func ImportRedirects(w *wrapper.Wrapper, args []string) error {
+	if len(args) < 1 {
+		return errors.New("Usage: import_redirects <site> <file.csv>")
+	}
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	n, errs := redirects.ImportCSV(f, w)
+	for _, e := range errs {
+		fmt.Println(e)
+	}
+	fmt.Printf("Imported %d redirects\n", n)
+	if len(errs) > 0 {
+		return errors.New("Some redirects could not be imported")
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+// Compress negotiates brotli or gzip encoding of responses with the client.
+// Api payloads and the config script are compressed as they are written,
+// assets are served from precompressed .br and .gz files next to them.
+
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"github.com/andybalholm/brotli"
+	"github.com/mongolar/mongolar/configs"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Smallest response compressed when the site does not set CompressMinSize
+const DefaultMinSize = 1024
+
+// Content types compressed when the site does not set CompressTypes
+var DefaultTypes = []string{
+	"application/json",
+	"application/javascript",
+	"text/css",
+	"text/html",
+	"text/plain",
+	"image/svg+xml",
+}
+
+// Encodings in order of preference with the extension of precompressed files.
+var encodings = []struct {
+	name string
+	ext  string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// Pick the preferred encoding the client accepts from the available encodings.
+// Encodings the client gives a quality of 0 are refused.
+func Negotiate(r *http.Request, available ...string) string {
+	accepted := make(map[string]bool)
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		fields := strings.Split(part, ";")
+		name := strings.ToLower(strings.TrimSpace(fields[0]))
+		accepted[name] = true
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				q, err := strconv.ParseFloat(param[2:], 64)
+				if err == nil && q == 0 {
+					accepted[name] = false
+				}
+			}
+		}
+	}
+	for _, e := range encodings {
+		for _, a := range available {
+			if a == e.name && accepted[a] {
+				return a
+			}
+		}
+	}
+	return ""
+}
+
+// Check if a response should be compressed for a site.
+func Compressible(contenttype string, size int, s *configs.SiteConfig) bool {
+	min := s.CompressMinSize
+	if min == 0 {
+		min = DefaultMinSize
+	}
+	if min < 0 || size < min {
+		return false
+	}
+	types := s.CompressTypes
+	if len(types) == 0 {
+		types = DefaultTypes
+	}
+	mediatype, _, err := mime.ParseMediaType(contenttype)
+	if err != nil {
+		return false
+	}
+	for _, t := range types {
+		if t == mediatype {
+			return true
+		}
+	}
+	return false
+}
+
+// Write a response body, compressed when the site and client allow it.
+func Write(w http.ResponseWriter, r *http.Request, s *configs.SiteConfig, b []byte) {
+	h := w.Header()
+	if !Compressible(h.Get("Content-Type"), len(b), s) {
+		w.Write(b)
+		return
+	}
+	h.Add("Vary", "Accept-Encoding")
+	encoding := Negotiate(r, "br", "gzip")
+	if encoding == "" {
+		w.Write(b)
+		return
+	}
+	var buf bytes.Buffer
+	var err error
+	if encoding == "br" {
+		bw := brotli.NewWriterLevel(&buf, brotli.DefaultCompression)
+		_, err = bw.Write(b)
+		if err == nil {
+			err = bw.Close()
+		}
+	} else {
+		gw := gzip.NewWriter(&buf)
+		_, err = gw.Write(b)
+		if err == nil {
+			err = gw.Close()
+		}
+	}
+	if err != nil {
+		w.Write(b)
+		return
+	}
+	h.Set("Content-Encoding", encoding)
+	h.Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.Write(buf.Bytes())
+}
+
+// Serve a file, or its precompressed .br or .gz sibling when the client
+// accepts it.  The content type is always taken from the original file.
+// Request paths with .. segments are rejected like http.ServeFile does.
+func ServeFile(w http.ResponseWriter, r *http.Request, f string) {
+	if containsDotDot(r.URL.Path) {
+		http.Error(w, "invalid URL path", http.StatusBadRequest)
+		return
+	}
+	available := make([]string, 0)
+	for _, e := range encodings {
+		if info, err := os.Stat(f + e.ext); err == nil && !info.IsDir() {
+			available = append(available, e.name)
+		}
+	}
+	if len(available) == 0 {
+		http.ServeFile(w, r, f)
+		return
+	}
+	w.Header().Add("Vary", "Accept-Encoding")
+	encoding := Negotiate(r, available...)
+	if encoding == "" {
+		http.ServeFile(w, r, f)
+		return
+	}
+	ext := ".gz"
+	if encoding == "br" {
+		ext = ".br"
+	}
+	file, err := os.Open(f + ext)
+	if err != nil {
+		http.ServeFile(w, r, f)
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		http.ServeFile(w, r, f)
+		return
+	}
+	contenttype := mime.TypeByExtension(filepath.Ext(f))
+	if contenttype == "" {
+		contenttype = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contenttype)
+	w.Header().Set("Content-Encoding", encoding)
+	http.ServeContent(w, r, f, info.ModTime(), file)
+}
+
+func containsDotDot(v string) bool {
+	if !strings.Contains(v, "..") {
+		return false
+	}
+	for _, ent := range strings.FieldsFunc(v, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if ent == ".." {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package compress
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		accept    string
+		available []string
+		encoding  string
+	}{
+		{"", []string{"br", "gzip"}, ""},
+		{"gzip", []string{"br", "gzip"}, "gzip"},
+		{"gzip, deflate, br", []string{"br", "gzip"}, "br"},
+		{"gzip, br", []string{"gzip"}, "gzip"},
+		{"GZIP", []string{"gzip"}, "gzip"},
+		{"br;q=0, gzip", []string{"br", "gzip"}, "gzip"},
+		{"br; q=0, gzip;q=0.5", []string{"br", "gzip"}, "gzip"},
+		{"br;q=0.0, gzip;q=0.000", []string{"br", "gzip"}, ""},
+		{"br;q=0.1", []string{"br", "gzip"}, "br"},
+		{"br;q=invalid", []string{"br"}, "br"},
+		{"gzip;q=0", []string{"gzip"}, ""},
+		{"deflate", []string{"br", "gzip"}, ""},
+		{"br, gzip", []string{}, ""},
+	}
+	for _, test := range tests {
+		r, _ := http.NewRequest("GET", "/", nil)
+		if test.accept != "" {
+			r.Header.Set("Accept-Encoding", test.accept)
+		}
+		e := Negotiate(r, test.available...)
+		if e != test.encoding {
+			t.Errorf("%q from %v: negotiated %q, expected %q", test.accept, test.available, e, test.encoding)
+		}
+	}
+}
@@ -14,10 +14,14 @@ var ServerConfigDirectory string
 // 	Port: Which port should be served
 // 	SitesDirectory: Where the individual sites folder is located
 // 	Log Directory: The directory where logs are stored
+// 	AdminPort: Port metrics are served on, they are not served when it is empty
+// 	AdminAddress: Address the admin port is bound to, defaults to 127.0.0.1
 type Server struct {
 	Port           string
 	SitesDirectory string
 	LogDirectory   string
+	AdminPort      string
+	AdminAddress   string
 }
 
 // Constructor for server config
@@ -45,4 +49,7 @@ func (s *Server) getServerConfig() {
 	if s.LogDirectory == "" {
 		log.Fatal("No Mongolar sites directory set.")
 	}
+	if s.AdminAddress == "" {
+		s.AdminAddress = "127.0.0.1"
+	}
 }
@@ -38,26 +38,69 @@ import (
 // 	Controllers: This is a list of valid controller map end points that are
 //		valid.  This is so you can establish per site functionality
 // 	ElementControllers: Elements availabled to be created in the UI
+// 	TrashRetention: Days deleted paths and elements are kept in the trash
+// 	MediaStore: The blob store for uploaded media, defaults to "file"
+// 	MediaDirectory: Where the file store keeps media, defaults to Directory/media
+// 	MediaSizes: Image variant sizes by name as "<width>x<height>"
+// 	MediaMaxPixels: Largest image that can be uploaded in pixels, defaults to 40000000
+// 	ElementCacheSize: Elements cached in memory, defaults to 10000, negative disables
+// 	ElementCacheBytes: Memory used by the element cache, defaults to 32MB
+// 	CacheMaxAge: Seconds public element responses can be cached by controller
+// 	PurgeEndpoints: Caches to purge when elements and paths change
+// 	PurgeRetries: Attempts to purge an endpoint before giving up, defaults to 3
+// 	CompressMinSize: Smallest response compressed in bytes, defaults to 1024, negative disables
+// 	CompressTypes: Content types compressed, defaults to json, javascript, css, html, text and svg
+// 	HTTPMiddleware: Names of the middleware wrapping every request to the site, outermost first
+// 	ControllerMiddleware: Names of the middleware wrapping every api controller, outermost first
+// 	LogFormat: "json" or "logfmt" for the site log, defaults to logfmt
+// 	Name: The name of the site configuration file, set when it is loaded
 // 	Logger:	Logrus logger
 // 	DbSession: The master MongoDb session that gets copied
 // 	RawConfig: Raw viper configuration
 
 type SiteConfig struct {
-	MongoDb            map[string]string
-	Directory          string
-	Aliases            []string
-	SessionExpiration  time.Duration
-	TemplateEndpoint   string
-	ForeignDomains     []string
-	AngularModules     []string
-	PublicValues       map[string]string
-	FourOFour          string
-	APIEndPoint        string
-	Controllers        []string
-	ElementControllers []string
-	Logger             *logrus.Logger
-	DbSession          *mgo.Session
-	RawConfig          *viper.Viper
+	MongoDb              map[string]string
+	Directory            string
+	Aliases              []string
+	SessionExpiration    time.Duration
+	TemplateEndpoint     string
+	ForeignDomains       []string
+	AngularModules       []string
+	PublicValues         map[string]string
+	FourOFour            string
+	APIEndPoint          string
+	Controllers          []string
+	ElementControllers   []string
+	TrashRetention       int
+	MediaStore           string
+	MediaDirectory       string
+	MediaSizes           map[string]string
+	MediaMaxPixels       int
+	ElementCacheSize     int
+	ElementCacheBytes    int
+	CacheMaxAge          map[string]int
+	PurgeEndpoints       []PurgeEndpoint
+	PurgeRetries         int
+	CompressMinSize      int
+	CompressTypes        []string
+	HTTPMiddleware       []string
+	ControllerMiddleware []string
+	LogFormat            string
+	Name                 string
+	Logger               *logrus.Logger
+	DbSession            *mgo.Session
+	RawConfig            *viper.Viper
+}
+
+// A cache to purge
+// 	Type: "purge" and "ban" send PURGE and BAN requests like Varnish expects,
+//		"http" posts the changed urls as json and "log" only logs them
+// 	URL: Where requests are sent
+// 	Headers: Added to every request, for api keys
+type PurgeEndpoint struct {
+	Type    string
+	URL     string
+	Headers map[string]string
 }
 
 // Constructor for SiteConfig, takes config filename as an argument.
@@ -67,6 +110,7 @@ func NewSiteConfig(f string) *SiteConfig {
 	}
 	// Marshall config based on filename
 	s.getSiteConfig(f)
+	s.Name = f
 	s.getDbConnection()
 	// Set log file based on config filename
 	s.getLogger(f)
@@ -102,5 +146,5 @@ func (s *SiteConfig) getDbConnection() {
 
 // Attach a logger channel to log errors predictably.
 func (s *SiteConfig) getLogger(f string) {
-	s.Logger = logger.New(ServerConfig.LogDirectory + f)
+	s.Logger = logger.New(ServerConfig.LogDirectory+f, s.LogFormat)
 }
@@ -16,3 +16,18 @@ type ControllerMap map[string]func(*wrapper.Wrapper)
 func NewMap() ControllerMap {
 	return make(ControllerMap)
 }
+
+// Names of controllers that only serve public cached responses, they get a
+// wrapper without a session.
+var Public = make(map[string]bool)
+
+// Middleware wraps a controller to run code before or after it, or instead of it.
+type Middleware func(func(*wrapper.Wrapper)) func(*wrapper.Wrapper)
+
+// Wrap a controller in middleware, the first middleware is the outermost.
+func Chain(c func(*wrapper.Wrapper), m ...Middleware) func(*wrapper.Wrapper) {
+	for i := len(m) - 1; i >= 0; i-- {
+		c = m[i](c)
+	}
+	return c
+}
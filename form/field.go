@@ -11,6 +11,7 @@ type Field struct {
 }
 
 type TemplateOptions struct {
+	Type        string              `json:"type,omitempty" bson:"type,omitempty"`
 	Options     []map[string]string `json:"options,omitempty" bson:"options,omitempty"`
 	Label       string              `json:"label,omitempty" bson:"label"`
 	Required    bool                `json:"required,omitempty" bson:"required"`
@@ -19,6 +20,8 @@ type TemplateOptions struct {
 	Cols        int                 `json:"cols,omitempty" bson:"cols,omitempty"`
 	Fields      []*Field            `json:"fields,omitempty" bson:"fields,omitempty"`
 	ButtonText  string              `json:"btnText,omitempty" bson:"btnText,omitempty"`
+	ContentType string              `json:"contentType,omitempty" bson:"content_type,omitempty"`
+	Multiple    bool                `json:"multiple,omitempty" bson:"multiple,omitempty"`
 }
 
 // Add label to field
@@ -25,12 +25,15 @@ func NewForm() *Form {
 	return &f
 }
 
-// Add a text field to form
+// Add a text field to form, t is the html input type (text, number, email...)
 func (f *Form) AddText(k string, t string) *Field {
+	fo := &TemplateOptions{
+		Type: t,
+	}
 	fi := &Field{
 		Type:            "input",
 		Key:             k,
-		TemplateOptions: new(TemplateOptions),
+		TemplateOptions: fo,
 	}
 	f.Fields = append(f.Fields, fi)
 	return fi
@@ -101,6 +104,22 @@ func (f *Form) AddRepeatSection(k string, b string, fs []*Field) *Field {
 	return fi
 }
 
+// Add a reference to other content elements, ct restricts the content type
+// of the referenced elements and m allows more than one reference.
+func (f *Form) AddReference(k string, ct string, m bool) *Field {
+	fo := &TemplateOptions{
+		ContentType: ct,
+		Multiple:    m,
+	}
+	fi := &Field{
+		Type:            "reference",
+		Key:             k,
+		TemplateOptions: fo,
+	}
+	f.Fields = append(f.Fields, fi)
+	return fi
+}
+
 // Register the form in the database
 func (f *Form) Register(w *wrapper.Wrapper) error {
 	fr := FormRegister{
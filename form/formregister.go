@@ -4,9 +4,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/mongolar/mongolar/metrics"
 	"github.com/mongolar/mongolar/services"
 	"github.com/mongolar/mongolar/wrapper"
 	"gopkg.in/mgo.v2/bson"
+	"io/ioutil"
 	"time"
 )
 
@@ -19,12 +21,12 @@ type FormRegister struct {
 }
 
 func GetValidFormData(w *wrapper.Wrapper, post interface{}) error {
-	p := make([]byte, w.Request.ContentLength)
-	_, err := w.Request.Body.Read(p)
+	p, err := ioutil.ReadAll(w.Request.Body)
 	if err != nil {
 		errmessage := fmt.Sprintf("Error processing post values %s: %s", w.Request.Host, err.Error())
-		w.SiteConfig.Logger.Error(errmessage)
+		w.Logger.Error(errmessage)
 		services.AddMessage("There was an issue processing your form.", "Error", w)
+		metrics.FormFailures.Inc(w.SiteConfig.Name, "read")
 		w.Serve()
 		return errors.New("Could not marshall Post values")
 	}
@@ -32,24 +34,28 @@ func GetValidFormData(w *wrapper.Wrapper, post interface{}) error {
 	err = json.Unmarshal(p, &data)
 	if err != nil {
 		errmessage := fmt.Sprintf("Error processing post values %s: %s", w.Request.Host, err.Error())
-		w.SiteConfig.Logger.Error(errmessage)
+		w.Logger.Error(errmessage)
 		services.AddMessage("There was an issue processing your form.", "Error", w)
+		metrics.FormFailures.Inc(w.SiteConfig.Name, "decode")
 		w.Serve()
 		return errors.New("Could not marshall Post values")
 	}
-	register, reg_err := GetFormRegister(data["form_id"].(string), w)
+	formid, _ := data["form_id"].(string)
+	register, reg_err := GetFormRegister(formid, w)
 	if reg_err != nil {
-		errmessage := fmt.Sprintf("Invalid or expired form %s: %s", w.Request.Host, err.Error())
-		w.SiteConfig.Logger.Error(errmessage)
+		errmessage := fmt.Sprintf("Invalid or expired form %s: %s", w.Request.Host, reg_err.Error())
+		w.Logger.Error(errmessage)
 		services.AddMessage("Your form was expired, please try again.", "Error", w)
+		metrics.FormFailures.Inc(w.SiteConfig.Name, "expired")
 		w.Serve()
 		return errors.New("Inalid or expired form")
 	}
 	err = json.Unmarshal(p, post)
 	if err != nil {
 		errmessage := fmt.Sprintf("Error processing post values %s: %s", w.Request.Host, err.Error())
-		w.SiteConfig.Logger.Error(errmessage)
+		w.Logger.Error(errmessage)
 		services.AddMessage("There was an issue processing your form.", "Error", w)
+		metrics.FormFailures.Inc(w.SiteConfig.Name, "decode")
 		w.Serve()
 		return errors.New("Could not marshall Post values")
 	}
@@ -60,6 +66,7 @@ func GetValidFormData(w *wrapper.Wrapper, post interface{}) error {
 			message := fmt.Sprintf("%s is required.", label)
 			services.AddMessage(message, "Error", w)
 		}
+		metrics.FormFailures.Inc(w.SiteConfig.Name, "missing")
 		w.Serve()
 		return errors.New("missing fields")
 	}
@@ -83,14 +90,22 @@ func (fr *FormRegister) ValidateRequired(data map[string]interface{}) map[string
 // Retrieve a previously registered form by id
 func GetFormRegister(i string, w *wrapper.Wrapper) (*FormRegister, error) {
 	fr := new(FormRegister)
+	if !bson.IsObjectIdHex(i) {
+		return fr, errors.New("Invalid Id Hex")
+	}
 	c := w.DbSession.DB("").C("form_register")
+	start := time.Now()
 	err := c.FindId(bson.ObjectIdHex(i)).One(fr)
+	metrics.DBDuration.Since(start, w.SiteConfig.Name, "form_register")
 	return fr, err
 }
 
 // Retrieve valid form based on id and session id
 func GetValidRegForm(i string, w *wrapper.Wrapper) (*FormRegister, error) {
 	fr := new(FormRegister)
+	if !bson.IsObjectIdHex(i) {
+		return fr, errors.New("Invalid Id Hex")
+	}
 	c := w.DbSession.DB("").C("form_register")
 	b := bson.M{"session_id": w.Session.Id, "_id": bson.ObjectIdHex(i)}
 	err := c.Find(b).One(fr)
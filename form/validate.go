@@ -0,0 +1,266 @@
+package form
+
+import (
+	"fmt"
+	"gopkg.in/mgo.v2/bson"
+	"strconv"
+	"strings"
+)
+
+// Validate and coerce a set of values against form fields.
+// Keys that do not belong to a field are stripped, numbers and booleans are
+// coerced to their native types and required fields are enforced.
+// Returns the cleaned values and a map of field keys to error messages.
+func CoerceData(fields []*Field, data map[string]interface{}) (map[string]interface{}, map[string]string) {
+	clean := make(map[string]interface{})
+	errs := make(map[string]string)
+	for _, f := range fields {
+		v, ok := data[f.Key]
+		if !ok || isEmpty(v) {
+			if f.TemplateOptions != nil && f.TemplateOptions.Required {
+				errs[f.Key] = fmt.Sprintf("%s is required.", f.Label())
+			}
+			continue
+		}
+		if f.Type == "repeatSection" {
+			cv, sub := f.coerceRepeat(v)
+			for k, e := range sub {
+				errs[k] = e
+			}
+			if len(sub) == 0 {
+				clean[f.Key] = cv
+			}
+			continue
+		}
+		cv, err := f.Coerce(v)
+		if err != "" {
+			errs[f.Key] = err
+			continue
+		}
+		clean[f.Key] = cv
+	}
+	return clean, errs
+}
+
+// Returns the keys in data that do not belong to any field.
+func UnknownKeys(fields []*Field, data map[string]interface{}) []string {
+	known := make(map[string]bool)
+	for _, f := range fields {
+		known[f.Key] = true
+	}
+	unknown := make([]string, 0)
+	for k := range data {
+		if !known[k] {
+			unknown = append(unknown, k)
+		}
+	}
+	return unknown
+}
+
+// Label of the field, falling back to its key.
+func (f *Field) Label() string {
+	if f.TemplateOptions != nil && f.TemplateOptions.Label != "" {
+		return f.TemplateOptions.Label
+	}
+	return f.Key
+}
+
+// Coerce a single value to the type expected by the field.
+// Returns an error message if the value can not be coerced.
+func (f *Field) Coerce(v interface{}) (interface{}, string) {
+	switch f.Type {
+	case "checkbox":
+		b, ok := coerceBool(v)
+		if !ok {
+			return nil, fmt.Sprintf("%s must be true or false.", f.Label())
+		}
+		return b, ""
+	case "input":
+		if f.TemplateOptions != nil && f.TemplateOptions.Type == "number" {
+			n, ok := coerceNumber(v)
+			if !ok {
+				return nil, fmt.Sprintf("%s must be a number.", f.Label())
+			}
+			return n, ""
+		}
+		return coerceString(v, f)
+	case "textarea":
+		return coerceString(v, f)
+	case "radio", "select":
+		s, err := coerceString(v, f)
+		if err != "" {
+			return nil, err
+		}
+		if f.TemplateOptions == nil {
+			return s, ""
+		}
+		for _, opt := range f.TemplateOptions.Options {
+			if opt["value"] == s {
+				return s, ""
+			}
+		}
+		return nil, fmt.Sprintf("%s is not a valid option.", f.Label())
+	case "reference":
+		ids, ok := coerceIds(v)
+		if !ok {
+			return nil, fmt.Sprintf("%s must reference valid elements.", f.Label())
+		}
+		if f.TemplateOptions != nil && f.TemplateOptions.Multiple {
+			return ids, ""
+		}
+		if len(ids) != 1 {
+			return nil, fmt.Sprintf("%s must reference a single element.", f.Label())
+		}
+		return ids[0], ""
+	}
+	return v, ""
+}
+
+// Map every reference field in data, including those nested in repeat sections.
+// fn is called with the path of the field and the referenced ids, the value it
+// returns replaces the reference in the returned copy of data.
+func MapReferences(fields []*Field, data map[string]interface{}, fn func(string, *Field, []string) interface{}) map[string]interface{} {
+	return mapReferences("", fields, data, fn)
+}
+
+func mapReferences(prefix string, fields []*Field, data map[string]interface{}, fn func(string, *Field, []string) interface{}) map[string]interface{} {
+	mapped := make(map[string]interface{})
+	for k, v := range data {
+		mapped[k] = v
+	}
+	for _, f := range fields {
+		v, ok := data[f.Key]
+		if !ok {
+			continue
+		}
+		switch f.Type {
+		case "reference":
+			ids, _ := coerceIds(v)
+			mapped[f.Key] = fn(prefix+f.Key, f, ids)
+		case "repeatSection":
+			items, ok := v.([]interface{})
+			if !ok || f.TemplateOptions == nil {
+				continue
+			}
+			mitems := make([]interface{}, 0)
+			for i, item := range items {
+				if m, ok := item.(map[string]interface{}); ok {
+					p := fmt.Sprintf("%s%s.%d.", prefix, f.Key, i)
+					item = mapReferences(p, f.TemplateOptions.Fields, m, fn)
+				}
+				mitems = append(mitems, item)
+			}
+			mapped[f.Key] = mitems
+		}
+	}
+	return mapped
+}
+
+// Coerce every item of a repeat section against the nested fields.
+// Errors are keyed by the path to the nested field, e.g. "links.2.url".
+func (f *Field) coerceRepeat(v interface{}) ([]interface{}, map[string]string) {
+	clean := make([]interface{}, 0)
+	errs := make(map[string]string)
+	items, ok := v.([]interface{})
+	if !ok {
+		errs[f.Key] = fmt.Sprintf("%s must be a list.", f.Label())
+		return nil, errs
+	}
+	if f.TemplateOptions == nil {
+		return items, errs
+	}
+	for i, item := range items {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			errs[fmt.Sprintf("%s.%d", f.Key, i)] = fmt.Sprintf("%s contains an invalid item.", f.Label())
+			continue
+		}
+		ci, ierrs := CoerceData(f.TemplateOptions.Fields, m)
+		for k, e := range ierrs {
+			errs[fmt.Sprintf("%s.%d.%s", f.Key, i, k)] = e
+		}
+		clean = append(clean, ci)
+	}
+	return clean, errs
+}
+
+func isEmpty(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	if s, ok := v.(string); ok && strings.TrimSpace(s) == "" {
+		return true
+	}
+	return false
+}
+
+func coerceBool(v interface{}) (bool, bool) {
+	switch t := v.(type) {
+	case bool:
+		return t, true
+	case float64:
+		return t != 0, t == 0 || t == 1
+	case int:
+		return t != 0, t == 0 || t == 1
+	case string:
+		switch strings.ToLower(t) {
+		case "true", "1", "on", "yes":
+			return true, true
+		case "false", "0", "off", "no":
+			return false, true
+		}
+	}
+	return false, false
+}
+
+func coerceNumber(v interface{}) (float64, bool) {
+	switch t := v.(type) {
+	case float64:
+		return t, true
+	case int:
+		return float64(t), true
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSpace(t), 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// Reference values may be a single id or a list of ids.
+func coerceIds(v interface{}) ([]string, bool) {
+	ids := make([]string, 0)
+	switch t := v.(type) {
+	case string:
+		ids = append(ids, t)
+	case []string:
+		ids = append(ids, t...)
+	case []interface{}:
+		for _, i := range t {
+			id, ok := i.(string)
+			if !ok {
+				return nil, false
+			}
+			ids = append(ids, id)
+		}
+	default:
+		return nil, false
+	}
+	for _, id := range ids {
+		if !bson.IsObjectIdHex(id) {
+			return nil, false
+		}
+	}
+	return ids, true
+}
+
+func coerceString(v interface{}, f *Field) (interface{}, string) {
+	switch t := v.(type) {
+	case string:
+		return t, ""
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64), ""
+	case bool:
+		return strconv.FormatBool(t), ""
+	}
+	return nil, fmt.Sprintf("%s must be text.", f.Label())
+}
@@ -0,0 +1,90 @@
+package form
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCoerceData(t *testing.T) {
+	fields := []*Field{
+		&Field{Type: "input", Key: "title", TemplateOptions: &TemplateOptions{Label: "Title", Required: true}},
+		&Field{Type: "input", Key: "weight", TemplateOptions: &TemplateOptions{Label: "Weight", Type: "number"}},
+		&Field{Type: "checkbox", Key: "published", TemplateOptions: &TemplateOptions{Label: "Published"}},
+	}
+	tests := []struct {
+		name  string
+		data  map[string]interface{}
+		clean map[string]interface{}
+		errs  map[string]string
+	}{
+		{
+			name:  "native values",
+			data:  map[string]interface{}{"title": "Home", "weight": float64(2), "published": true},
+			clean: map[string]interface{}{"title": "Home", "weight": float64(2), "published": true},
+			errs:  map[string]string{},
+		},
+		{
+			name:  "strings are coerced",
+			data:  map[string]interface{}{"title": "Home", "weight": " 2.5 ", "published": "on"},
+			clean: map[string]interface{}{"title": "Home", "weight": 2.5, "published": true},
+			errs:  map[string]string{},
+		},
+		{
+			name:  "numbers as checkboxes",
+			data:  map[string]interface{}{"title": "Home", "published": float64(0)},
+			clean: map[string]interface{}{"title": "Home", "published": false},
+			errs:  map[string]string{},
+		},
+		{
+			name:  "unknown keys are stripped",
+			data:  map[string]interface{}{"title": "Home", "other": "value"},
+			clean: map[string]interface{}{"title": "Home"},
+			errs:  map[string]string{},
+		},
+		{
+			name:  "required missing",
+			data:  map[string]interface{}{"weight": float64(1)},
+			clean: map[string]interface{}{"weight": float64(1)},
+			errs:  map[string]string{"title": "Title is required."},
+		},
+		{
+			name:  "required blank",
+			data:  map[string]interface{}{"title": "  "},
+			clean: map[string]interface{}{},
+			errs:  map[string]string{"title": "Title is required."},
+		},
+		{
+			name:  "optional blank",
+			data:  map[string]interface{}{"title": "Home", "weight": "", "published": nil},
+			clean: map[string]interface{}{"title": "Home"},
+			errs:  map[string]string{},
+		},
+		{
+			name:  "invalid number",
+			data:  map[string]interface{}{"title": "Home", "weight": "heavy"},
+			clean: map[string]interface{}{"title": "Home"},
+			errs:  map[string]string{"weight": "Weight must be a number."},
+		},
+		{
+			name:  "invalid checkbox",
+			data:  map[string]interface{}{"title": "Home", "published": "maybe"},
+			clean: map[string]interface{}{"title": "Home"},
+			errs:  map[string]string{"published": "Published must be true or false."},
+		},
+		{
+			name:  "checkbox number out of range",
+			data:  map[string]interface{}{"title": "Home", "published": float64(2)},
+			clean: map[string]interface{}{"title": "Home"},
+			errs:  map[string]string{"published": "Published must be true or false."},
+		},
+	}
+	for _, test := range tests {
+		clean, errs := CoerceData(fields, test.data)
+		if !reflect.DeepEqual(clean, test.clean) {
+			t.Errorf("%s: clean is %v, expected %v", test.name, clean, test.clean)
+		}
+		if !reflect.DeepEqual(errs, test.errs) {
+			t.Errorf("%s: errors are %v, expected %v", test.name, errs, test.errs)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+// Health answers load balancers.  /healthz says the process is up, /readyz
+// checks every site can reach its database, write its log and has its
+// configuration loaded, /readyz/<site> checks one site.  The detailed checks
+// are only served on the admin port, the public port answers /healthz and a
+// cached /readyz without details before the host based router so they work
+// without a site alias.
+
+package health
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/mongolar/mongolar/configs"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How long a database ping can take before a site is not ready.
+var PingTimeout = 2 * time.Second
+
+// How long the public port reuses a readiness result.
+var PublicCacheFor = 5 * time.Second
+
+// The result of checking one site, checks are "ok" or the error.
+type SiteStatus struct {
+	Ready  bool              `json:"ready"`
+	Checks map[string]string `json:"checks"`
+}
+
+// The result of checking every site.
+type Status struct {
+	Ready bool                   `json:"ready"`
+	Sites map[string]*SiteStatus `json:"sites"`
+}
+
+// Add the health handlers to a mux.
+func Handle(mux *http.ServeMux, sm configs.SitesMap) {
+	ready := Readyz(sm)
+	mux.HandleFunc("/healthz", Healthz)
+	mux.Handle("/readyz", ready)
+	mux.Handle("/readyz/", ready)
+}
+
+// Answer public health checks for any host and pass everything else to
+// next.  Readiness is only ready or not and is checked at most once every
+// PublicCacheFor, so site names and errors are never shown and requests can
+// not make every site ping its database.
+func Handler(next http.Handler, sm configs.SitesMap) http.Handler {
+	var lock sync.Mutex
+	var ready bool
+	var checked time.Time
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/healthz":
+			Healthz(w, r)
+		case "/readyz":
+			lock.Lock()
+			if time.Since(checked) > PublicCacheFor {
+				ready = CheckAll(sm).Ready
+				checked = time.Now()
+			}
+			rd := ready
+			lock.Unlock()
+			write(w, code(rd), map[string]bool{"ready": rd})
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// Answer as long as the process is up.
+func Healthz(w http.ResponseWriter, r *http.Request) {
+	write(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// Check every site, or the site named after /readyz/.  Anything not ready
+// is answered with a 503.
+func Readyz(sm configs.SitesMap) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/readyz"), "/")
+		if name != "" {
+			s, ok := sm[name]
+			if !ok {
+				write(w, http.StatusNotFound, map[string]string{"error": "Unknown site " + name})
+				return
+			}
+			st := Check(s)
+			write(w, code(st.Ready), st)
+			return
+		}
+		st := CheckAll(sm)
+		write(w, code(st.Ready), st)
+	})
+}
+
+// Check every site at the same time.
+func CheckAll(sm configs.SitesMap) *Status {
+	st := &Status{Ready: len(sm) > 0, Sites: make(map[string]*SiteStatus)}
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	for name, s := range sm {
+		wg.Add(1)
+		go func(name string, s *configs.SiteConfig) {
+			defer wg.Done()
+			ss := Check(s)
+			lock.Lock()
+			st.Sites[name] = ss
+			if !ss.Ready {
+				st.Ready = false
+			}
+			lock.Unlock()
+		}(name, s)
+	}
+	wg.Wait()
+	return st
+}
+
+// Check one site.
+func Check(s *configs.SiteConfig) *SiteStatus {
+	ss := &SiteStatus{Ready: true, Checks: make(map[string]string)}
+	ss.set("config", checkConfig(s))
+	ss.set("mongodb", checkDb(s))
+	ss.set("log", checkLog(s))
+	return ss
+}
+
+func (ss *SiteStatus) set(name string, err error) {
+	if err != nil {
+		ss.Checks[name] = err.Error()
+		ss.Ready = false
+		return
+	}
+	ss.Checks[name] = "ok"
+}
+
+func checkConfig(s *configs.SiteConfig) error {
+	if configs.ServerConfig == nil || s.RawConfig == nil {
+		return errors.New("configuration not loaded")
+	}
+	if s.APIEndPoint == "" || len(s.Aliases) == 0 {
+		return errors.New("configuration has no APIEndPoint or Aliases")
+	}
+	return nil
+}
+
+func checkDb(s *configs.SiteConfig) error {
+	if s.DbSession == nil {
+		return errors.New("no database session")
+	}
+	dbs := s.DbSession.Copy()
+	defer dbs.Close()
+	dbs.SetSyncTimeout(PingTimeout)
+	dbs.SetSocketTimeout(PingTimeout)
+	return dbs.Ping()
+}
+
+// The log is opened for appending the way the logger opens it.
+func checkLog(s *configs.SiteConfig) error {
+	if configs.ServerConfig == nil {
+		return errors.New("server configuration not loaded")
+	}
+	f, err := os.OpenFile(configs.ServerConfig.LogDirectory+s.Name, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func code(ready bool) int {
+	if ready {
+		return http.StatusOK
+	}
+	return http.StatusServiceUnavailable
+}
+
+func write(w http.ResponseWriter, code int, v interface{}) {
+	js, _ := json.Marshal(v)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	w.WriteHeader(code)
+	w.Write(js)
+}
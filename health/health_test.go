@@ -0,0 +1,98 @@
+package health
+
+import (
+	"github.com/mongolar/mongolar/configs"
+	"github.com/spf13/viper"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	dir, err := ioutil.TempDir("", "health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	server := configs.ServerConfig
+	configs.ServerConfig = &configs.Server{LogDirectory: dir + "/"}
+	defer func() { configs.ServerConfig = server }()
+	err = ioutil.WriteFile(filepath.Join(dir, "site"), nil, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &configs.SiteConfig{Name: "site", RawConfig: viper.New(), APIEndPoint: "api", Aliases: []string{"example.com"}}
+	ss := Check(s)
+	if ss.Ready {
+		t.Errorf("a site without a database is ready")
+	}
+	if ss.Checks["config"] != "ok" || ss.Checks["log"] != "ok" {
+		t.Errorf("checks are %v, expected config and log ok", ss.Checks)
+	}
+	if ss.Checks["mongodb"] != "no database session" {
+		t.Errorf("mongodb check is %s", ss.Checks["mongodb"])
+	}
+	s = &configs.SiteConfig{Name: "missing", RawConfig: viper.New()}
+	ss = Check(s)
+	if ss.Checks["config"] == "ok" || ss.Checks["log"] == "ok" {
+		t.Errorf("checks are %v, expected config and log errors", ss.Checks)
+	}
+	configs.ServerConfig = nil
+	ss = Check(&configs.SiteConfig{Name: "site", RawConfig: viper.New(), APIEndPoint: "api", Aliases: []string{"example.com"}})
+	if ss.Checks["config"] != "configuration not loaded" {
+		t.Errorf("config check without a server configuration is %s", ss.Checks["config"])
+	}
+	st := CheckAll(configs.SitesMap{})
+	if st.Ready {
+		t.Errorf("a server without sites is ready")
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	sm := configs.SitesMap{"site": &configs.SiteConfig{Name: "site"}}
+	tests := []struct {
+		path string
+		code int
+	}{
+		{"/readyz", http.StatusServiceUnavailable},
+		{"/readyz/site", http.StatusServiceUnavailable},
+		{"/readyz/other", http.StatusNotFound},
+	}
+	for _, test := range tests {
+		rec := httptest.NewRecorder()
+		Readyz(sm).ServeHTTP(rec, httptest.NewRequest("GET", test.path, nil))
+		if rec.Code != test.code {
+			t.Errorf("%s: status is %d, expected %d", test.path, rec.Code, test.code)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	h := Handler(next, configs.SitesMap{})
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/healthz", http.StatusOK, `{"status":"ok"}`},
+		{"/readyz", http.StatusServiceUnavailable, `{"ready":false}`},
+		{"/readyz/site", http.StatusTeapot, ""},
+		{"/", http.StatusTeapot, ""},
+	}
+	for _, test := range tests {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", test.path, nil))
+		if rec.Code != test.code {
+			t.Errorf("%s: status is %d, expected %d", test.path, rec.Code, test.code)
+		}
+		if rec.Body.String() != test.body {
+			t.Errorf("%s: body is %s, expected %s", test.path, rec.Body.String(), test.body)
+		}
+	}
+}
@@ -0,0 +1,408 @@
+// Integrity scans a site for references that no longer resolve and structures
+// the controllers can not render, and optionally repairs them.
+// Problems found
+// 	dangling: A path, wrapper, slug or reference field points to an element that does not exist
+// 	shape: An element's controller_values do not match its controller
+// 	cycle: A wrapper or slug contains itself through its children
+// 	duplicate: More than one path matches the same url, wildcard and status
+// Repair removes dangling ids and breaks cycles, shape problems and duplicate
+// paths are only reported because an editor has to decide what to keep.
+
+package integrity
+
+import (
+	"fmt"
+	"github.com/mongolar/mongolar/form"
+	"github.com/mongolar/mongolar/models/contenttypes"
+	"github.com/mongolar/mongolar/models/elements"
+	"github.com/mongolar/mongolar/models/paths"
+	"github.com/mongolar/mongolar/models/references"
+	"github.com/mongolar/mongolar/wrapper"
+	"gopkg.in/mgo.v2/bson"
+	"sort"
+)
+
+// Kinds of problems
+const (
+	Dangling  = "dangling"
+	Shape     = "shape"
+	Cycle     = "cycle"
+	Duplicate = "duplicate"
+)
+
+// A single problem in a document
+type Problem struct {
+	Kind       string `json:"kind"`
+	Collection string `json:"collection"`
+	Id         string `json:"id"`
+	Field      string `json:"field,omitempty"`
+	Target     string `json:"target,omitempty"`
+	Message    string `json:"message"`
+	Repaired   bool   `json:"repaired"`
+}
+
+// The result of a scan
+type Report struct {
+	Problems []Problem `json:"problems"`
+	Errors   []string  `json:"errors,omitempty"`
+}
+
+// Scan the site and report all problems without changing anything.
+func Check(w *wrapper.Wrapper) (Report, error) {
+	return scan(false, w)
+}
+
+// Scan the site and repair the problems that can be repaired.
+// Repairs that fail are listed in the report errors.
+func Repair(w *wrapper.Wrapper) (Report, error) {
+	r, err := scan(true, w)
+	paths.Invalidate(w.SiteConfig)
+	elements.InvalidateAll(w)
+	return r, err
+}
+
+type scanner struct {
+	repair   bool
+	w        *wrapper.Wrapper
+	report   *Report
+	elements map[string]bson.M
+	children map[string][]string
+}
+
+func scan(repair bool, w *wrapper.Wrapper) (Report, error) {
+	r := Report{Problems: make([]Problem, 0)}
+	s := &scanner{
+		repair:   repair,
+		w:        w,
+		report:   &r,
+		elements: make(map[string]bson.M),
+		children: make(map[string][]string),
+	}
+	docs := make([]bson.M, 0)
+	err := w.DbSession.DB("").C("elements").Find(nil).All(&docs)
+	if err != nil {
+		return r, err
+	}
+	for _, d := range docs {
+		if id, ok := d["_id"].(bson.ObjectId); ok {
+			s.elements[id.Hex()] = d
+		}
+	}
+	ps := make([]bson.M, 0)
+	err = w.DbSession.DB("").C("paths").Find(nil).All(&ps)
+	if err != nil {
+		return r, err
+	}
+	for _, id := range sortedIds(s.elements) {
+		s.element(id, s.elements[id])
+	}
+	for _, p := range ps {
+		s.path(p)
+	}
+	err = s.content()
+	if err != nil {
+		return r, err
+	}
+	err = s.references()
+	if err != nil {
+		return r, err
+	}
+	s.cycles()
+	s.duplicates(ps)
+	return r, nil
+}
+
+// Check the shape of an element and the children of wrappers and slugs.
+func (s *scanner) element(id string, d bson.M) {
+	cv := d["controller_values"]
+	switch d["controller"] {
+	case "wrapper":
+		m, ok := doc(cv)
+		list, lok := m["elements"].([]interface{})
+		if !ok || (m["elements"] != nil && !lok) {
+			s.problem(Problem{Kind: Shape, Collection: "elements", Id: id, Message: "Wrapper values must hold an elements list"})
+			return
+		}
+		for _, item := range list {
+			eid, ok := item.(string)
+			if !ok {
+				s.problem(Problem{Kind: Shape, Collection: "elements", Id: id, Message: "Wrapper elements must be ids"})
+				continue
+			}
+			if _, ok := s.elements[eid]; !ok {
+				p := Problem{Kind: Dangling, Collection: "elements", Id: id, Field: "controller_values.elements", Target: eid, Message: "Wrapper contains a missing element"}
+				s.fix(&p, bson.M{"$pull": bson.M{"controller_values.elements": eid}})
+				continue
+			}
+			s.children[id] = append(s.children[id], eid)
+		}
+	case "slug":
+		m, ok := doc(cv)
+		if !ok {
+			s.problem(Problem{Kind: Shape, Collection: "elements", Id: id, Message: "Slug values must map slugs to element ids"})
+			return
+		}
+		for _, slug := range sortedKeys(m) {
+			eid, ok := m[slug].(string)
+			if !ok {
+				s.problem(Problem{Kind: Shape, Collection: "elements", Id: id, Field: slug, Message: "Slug values must be ids"})
+				continue
+			}
+			if _, ok := s.elements[eid]; !ok {
+				p := Problem{Kind: Dangling, Collection: "elements", Id: id, Field: "controller_values." + slug, Target: eid, Message: "Slug points to a missing element"}
+				s.fix(&p, bson.M{"$unset": bson.M{"controller_values." + slug: ""}})
+				continue
+			}
+			s.children[id] = append(s.children[id], eid)
+		}
+	case "content":
+		m, ok := doc(cv)
+		t, tok := m["type"].(string)
+		_, cok := doc(m["content"])
+		if !ok || !tok || t == "" || (m["content"] != nil && !cok) {
+			s.problem(Problem{Kind: Shape, Collection: "elements", Id: id, Message: "Content values must hold a type and content"})
+		}
+	case "menu":
+		items, ok := cv.([]interface{})
+		if !ok && cv != nil {
+			s.problem(Problem{Kind: Shape, Collection: "elements", Id: id, Message: "Menu values must be a list of menu items"})
+			return
+		}
+		for _, item := range items {
+			if _, ok := doc(item); !ok {
+				s.problem(Problem{Kind: Shape, Collection: "elements", Id: id, Message: "Menu items must be documents"})
+				return
+			}
+		}
+	}
+}
+
+// Check the elements of a path.
+func (s *scanner) path(d bson.M) {
+	id := hex(d["_id"])
+	list, _ := d["elements"].([]interface{})
+	for _, item := range list {
+		eid, _ := item.(string)
+		if _, ok := s.elements[eid]; ok {
+			continue
+		}
+		p := Problem{Kind: Dangling, Collection: "paths", Id: id, Field: "elements", Target: eid, Message: "Path contains a missing element"}
+		if s.repair {
+			err := s.w.DbSession.DB("").C("paths").UpdateId(d["_id"], bson.M{"$pull": bson.M{"elements": item}})
+			s.repaired(&p, err)
+		}
+		s.problem(p)
+	}
+}
+
+// Check reference fields of content elements against their content type.
+func (s *scanner) content() error {
+	ces := make([]elements.ContentElement, 0)
+	err := s.w.DbSession.DB("").C("elements").Find(bson.M{"controller": "content"}).All(&ces)
+	if err != nil {
+		return err
+	}
+	cts := make(map[string]*contenttypes.ContentType)
+	for _, ce := range ces {
+		ct, ok := cts[ce.Type]
+		if !ok {
+			loaded, err := contenttypes.LoadContentTypeT(ce.Type, s.w)
+			if err == nil {
+				ct = &loaded
+			}
+			cts[ce.Type] = ct
+		}
+		if ct == nil {
+			continue
+		}
+		id := ce.MongoId.Hex()
+		missing := make([]Problem, 0)
+		for field, ids := range ct.References(ce.Content) {
+			for _, rid := range ids {
+				if _, ok := s.elements[rid]; !ok {
+					missing = append(missing, Problem{Kind: Dangling, Collection: "elements", Id: id, Field: "controller_values.content." + field, Target: rid, Message: "Reference field points to a missing element"})
+				}
+			}
+		}
+		if len(missing) == 0 {
+			continue
+		}
+		var err error
+		if s.repair {
+			err = s.removeReferences(ce, ct)
+		}
+		for _, p := range missing {
+			if s.repair {
+				s.repaired(&p, err)
+			}
+			s.problem(p)
+		}
+	}
+	return nil
+}
+
+// Remove missing ids from the reference fields of a content element.
+func (s *scanner) removeReferences(ce elements.ContentElement, ct *contenttypes.ContentType) error {
+	content := form.MapReferences(ct.Form, ce.Content, func(p string, f *form.Field, ids []string) interface{} {
+		kept := make([]string, 0)
+		for _, rid := range ids {
+			if _, ok := s.elements[rid]; ok {
+				kept = append(kept, rid)
+			}
+		}
+		if f.TemplateOptions != nil && f.TemplateOptions.Multiple {
+			return kept
+		}
+		if len(kept) == 0 {
+			return nil
+		}
+		return kept[0]
+	})
+	err := s.w.DbSession.DB("").C("elements").UpdateId(ce.MongoId, elements.Stamp(bson.M{"$set": bson.M{"controller_values.content": content}}))
+	if err != nil {
+		return err
+	}
+	return references.Set(ce.MongoId.Hex(), ct.References(content), s.w)
+}
+
+// Check the reference index for entries from elements that no longer exist.
+func (s *scanner) references() error {
+	rs := make([]references.Reference, 0)
+	err := s.w.DbSession.DB("").C("references").Find(nil).All(&rs)
+	if err != nil {
+		return err
+	}
+	for _, r := range rs {
+		if _, ok := s.elements[r.Source]; ok {
+			continue
+		}
+		p := Problem{Kind: Dangling, Collection: "references", Id: r.MongoId.Hex(), Field: "source", Target: r.Source, Message: "Reference from a missing element"}
+		if s.repair {
+			err := s.w.DbSession.DB("").C("references").RemoveId(r.MongoId)
+			s.repaired(&p, err)
+		}
+		s.problem(p)
+	}
+	return nil
+}
+
+// Find wrappers and slugs that contain themselves, repair removes the child
+// that closes the cycle.
+func (s *scanner) cycles() {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int)
+	var visit func(id string, chain []string)
+	visit = func(id string, chain []string) {
+		state[id] = visiting
+		chain = append(chain, id)
+		for _, child := range s.children[id] {
+			switch state[child] {
+			case visiting:
+				p := Problem{Kind: Cycle, Collection: "elements", Id: id, Target: child, Message: fmt.Sprintf("Cycle %v", append(chain, child))}
+				if s.elements[id]["controller"] == "slug" {
+					p.Field = "controller_values"
+					s.fix(&p, bson.M{"$unset": slugUnset(s.elements[id], child)})
+				} else {
+					p.Field = "controller_values.elements"
+					s.fix(&p, bson.M{"$pull": bson.M{"controller_values.elements": child}})
+				}
+			case unvisited:
+				visit(child, chain)
+			}
+		}
+		state[id] = done
+	}
+	for _, id := range sortedIds(s.elements) {
+		if state[id] == unvisited {
+			visit(id, nil)
+		}
+	}
+}
+
+// Find paths with the same url, wildcard and status.
+func (s *scanner) duplicates(ps []bson.M) {
+	seen := make(map[string]string)
+	for _, d := range ps {
+		key := fmt.Sprintf("%v|%v|%v", d["path"], d["wildcard"], d["status"])
+		id := hex(d["_id"])
+		if first, ok := seen[key]; ok {
+			s.problem(Problem{Kind: Duplicate, Collection: "paths", Id: id, Field: "path", Target: first, Message: fmt.Sprintf("Duplicate of path %v", d["path"])})
+			continue
+		}
+		seen[key] = id
+	}
+}
+
+// Apply an update to an element when repairing and record the problem.
+func (s *scanner) fix(p *Problem, update bson.M) {
+	if s.repair {
+		err := s.w.DbSession.DB("").C("elements").UpdateId(bson.ObjectIdHex(p.Id), elements.Stamp(update))
+		s.repaired(p, err)
+	}
+	s.problem(*p)
+}
+
+func (s *scanner) repaired(p *Problem, err error) {
+	if err != nil {
+		s.report.Errors = append(s.report.Errors, fmt.Sprintf("%s %s: %s", p.Collection, p.Id, err.Error()))
+		return
+	}
+	p.Repaired = true
+}
+
+func (s *scanner) problem(p Problem) {
+	s.report.Problems = append(s.report.Problems, p)
+}
+
+// The slugs of a slug element that point to a child.
+func slugUnset(d bson.M, child string) bson.M {
+	u := bson.M{}
+	m, _ := doc(d["controller_values"])
+	for slug, eid := range m {
+		if eid == child {
+			u["controller_values."+slug] = ""
+		}
+	}
+	return u
+}
+
+// Embedded documents are decoded as bson.M.
+func doc(v interface{}) (map[string]interface{}, bool) {
+	switch t := v.(type) {
+	case bson.M:
+		return t, true
+	case map[string]interface{}:
+		return t, true
+	}
+	return nil, false
+}
+
+func hex(v interface{}) string {
+	if id, ok := v.(bson.ObjectId); ok {
+		return id.Hex()
+	}
+	return fmt.Sprint(v)
+}
+
+// Elements are scanned in id order so reports are stable.
+func sortedIds(m map[string]bson.M) []string {
+	ids := make([]string, 0)
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0)
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,89 @@
+package integrity
+
+import (
+	"gopkg.in/mgo.v2/bson"
+	"reflect"
+	"testing"
+)
+
+// A scanner that only reports, so it never needs a database.
+func testScanner(docs map[string]bson.M) *scanner {
+	s := &scanner{
+		report:   &Report{Problems: make([]Problem, 0)},
+		elements: docs,
+		children: make(map[string][]string),
+	}
+	for _, id := range sortedIds(docs) {
+		s.element(id, docs[id])
+	}
+	return s
+}
+
+func kinds(r *Report) []string {
+	k := make([]string, 0)
+	for _, p := range r.Problems {
+		k = append(k, p.Kind+" "+p.Id+" "+p.Target)
+	}
+	return k
+}
+
+func TestElements(t *testing.T) {
+	s := testScanner(map[string]bson.M{
+		"a": bson.M{"controller": "wrapper", "controller_values": bson.M{"elements": []interface{}{"b", "missing"}}},
+		"b": bson.M{"controller": "slug", "controller_values": bson.M{"home": "c", "gone": "missing"}},
+		"c": bson.M{"controller": "content", "controller_values": bson.M{"type": "page", "content": bson.M{}}},
+		"d": bson.M{"controller": "content", "controller_values": bson.M{"content": bson.M{}}},
+		"e": bson.M{"controller": "wrapper", "controller_values": "b"},
+		"f": bson.M{"controller": "menu", "controller_values": []interface{}{bson.M{"title": "Home"}, "x"}},
+	})
+	expected := []string{
+		"dangling a missing",
+		"dangling b missing",
+		"shape d ",
+		"shape e ",
+		"shape f ",
+	}
+	if k := kinds(s.report); !reflect.DeepEqual(k, expected) {
+		t.Errorf("problems are %v, expected %v", k, expected)
+	}
+	children := map[string][]string{"a": []string{"b"}, "b": []string{"c"}}
+	if !reflect.DeepEqual(s.children, children) {
+		t.Errorf("children are %v, expected %v", s.children, children)
+	}
+}
+
+func TestCycles(t *testing.T) {
+	s := testScanner(map[string]bson.M{
+		"a": bson.M{"controller": "wrapper", "controller_values": bson.M{"elements": []interface{}{"b"}}},
+		"b": bson.M{"controller": "slug", "controller_values": bson.M{"home": "a"}},
+		"c": bson.M{"controller": "wrapper", "controller_values": bson.M{"elements": []interface{}{"b"}}},
+	})
+	s.cycles()
+	expected := []string{"cycle b a"}
+	if k := kinds(s.report); !reflect.DeepEqual(k, expected) {
+		t.Errorf("problems are %v, expected %v", k, expected)
+	}
+	u := slugUnset(s.elements["b"], "a")
+	if !reflect.DeepEqual(u, bson.M{"controller_values.home": ""}) {
+		t.Errorf("slug unset is %v", u)
+	}
+}
+
+func TestPaths(t *testing.T) {
+	s := testScanner(map[string]bson.M{
+		"a": bson.M{"controller": "content", "controller_values": bson.M{"type": "page"}},
+	})
+	ps := []bson.M{
+		bson.M{"_id": "p1", "path": "/", "wildcard": false, "status": "published", "elements": []interface{}{"a"}},
+		bson.M{"_id": "p2", "path": "/", "wildcard": false, "status": "published", "elements": []interface{}{"missing"}},
+		bson.M{"_id": "p3", "path": "/", "wildcard": true, "status": "published"},
+	}
+	for _, p := range ps {
+		s.path(p)
+	}
+	s.duplicates(ps)
+	expected := []string{"dangling p2 missing", "duplicate p2 p1"}
+	if k := kinds(s.report); !reflect.DeepEqual(k, expected) {
+		t.Errorf("problems are %v, expected %v", k, expected)
+	}
+}
@@ -0,0 +1,204 @@
+// Listing pages, sorts and filters collections for list controllers so lists
+// are not limited to a fixed number of documents.
+// Paging uses a cursor instead of an offset so pages stay consistent while
+// documents are added or removed.
+//
+// Query parameters read from the request url
+// 	limit: The page size, defaults to DefaultLimit and can not exceed MaxLimit
+// 	cursor: The "next" value from the previous page
+// 	sort: A sortable field name, prefixed with "-" to sort descending
+// 	Any filter name defined by the list controller
+
+package listing
+
+import (
+	"encoding/base64"
+	"errors"
+	"github.com/mongolar/mongolar/wrapper"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	DefaultLimit = 50
+	MaxLimit     = 500
+)
+
+var ErrInvalidCursor = errors.New("Invalid cursor")
+
+// A filter maps a url parameter to a document field.
+// Partial filters match case insensitive substrings, others match exactly.
+type Filter struct {
+	Field   string
+	Partial bool
+}
+
+// Definition of what a list controller can be sorted and filtered on.
+// Sorts maps url sort names to document fields.
+type Definition struct {
+	Sorts   map[string]string
+	Filters map[string]Filter
+}
+
+// A parsed listing query
+type Query struct {
+	Filter     bson.M
+	Sort       string
+	Descending bool
+	Limit      int
+	Cursor     string
+}
+
+// Page metadata returned with every page
+type Page struct {
+	Next  string `json:"next,omitempty"`
+	Limit int    `json:"limit"`
+	Count int    `json:"count"`
+	Total int    `json:"total"`
+}
+
+// The position of the last document of a page, encoded as bson so sort
+// values like dates keep their type.
+type cursor struct {
+	Value interface{} `bson:"v,omitempty"`
+	Id    string      `bson:"id"`
+}
+
+// Build a query from the request url values.
+func New(w *wrapper.Wrapper, d Definition) Query {
+	v := w.Request.URL.Query()
+	q := Query{
+		Filter: bson.M{},
+		Sort:   "_id",
+		Limit:  DefaultLimit,
+		Cursor: v.Get("cursor"),
+	}
+	if l, err := strconv.Atoi(v.Get("limit")); err == nil && l > 0 {
+		q.Limit = l
+	}
+	if q.Limit > MaxLimit {
+		q.Limit = MaxLimit
+	}
+	s := v.Get("sort")
+	if strings.HasPrefix(s, "-") {
+		q.Descending = true
+		s = s[1:]
+	}
+	if field, ok := d.Sorts[s]; ok {
+		q.Sort = field
+	}
+	for name, f := range d.Filters {
+		fv := v.Get(name)
+		if fv == "" {
+			continue
+		}
+		if f.Partial {
+			q.Filter[f.Field] = bson.RegEx{Pattern: regexp.QuoteMeta(fv), Options: "i"}
+		} else {
+			q.Filter[f.Field] = fv
+		}
+	}
+	return q
+}
+
+// Run the query against a collection and load one page into result,
+// which must be a pointer to a slice.
+func (q Query) Run(c *mgo.Collection, result interface{}) (Page, error) {
+	p := Page{Limit: q.Limit}
+	total, err := c.Find(q.Filter).Count()
+	if err != nil {
+		return p, err
+	}
+	p.Total = total
+	s := bson.M{}
+	for k, v := range q.Filter {
+		s[k] = v
+	}
+	if q.Cursor != "" {
+		after, err := q.after()
+		if err != nil {
+			return p, err
+		}
+		s = bson.M{"$and": []bson.M{s, after}}
+	}
+	sort := []string{q.Sort, "_id"}
+	if q.Sort == "_id" {
+		sort = []string{"_id"}
+	}
+	if q.Descending {
+		for i := range sort {
+			sort[i] = "-" + sort[i]
+		}
+	}
+	raw := make([]bson.Raw, 0)
+	err = c.Find(s).Sort(sort...).Limit(q.Limit + 1).All(&raw)
+	if err != nil {
+		return p, err
+	}
+	if len(raw) > q.Limit {
+		raw = raw[:q.Limit]
+		p.Next, err = q.next(raw[len(raw)-1])
+		if err != nil {
+			return p, err
+		}
+	}
+	p.Count = len(raw)
+	rv := reflect.ValueOf(result).Elem()
+	rv.Set(reflect.MakeSlice(rv.Type(), 0, len(raw)))
+	for _, r := range raw {
+		ev := reflect.New(rv.Type().Elem())
+		err = r.Unmarshal(ev.Interface())
+		if err != nil {
+			return p, err
+		}
+		rv.Set(reflect.Append(rv, ev.Elem()))
+	}
+	return p, nil
+}
+
+// The cursor pointing past a document.
+func (q Query) next(r bson.Raw) (string, error) {
+	var d bson.M
+	err := r.Unmarshal(&d)
+	if err != nil {
+		return "", err
+	}
+	id, _ := d["_id"].(bson.ObjectId)
+	c := cursor{Id: id.Hex()}
+	if q.Sort != "_id" {
+		c.Value = d[q.Sort]
+	}
+	b, err := bson.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(b), nil
+}
+
+// The condition selecting documents after the cursor.
+func (q Query) after() (bson.M, error) {
+	var c cursor
+	b, err := base64.URLEncoding.DecodeString(q.Cursor)
+	if err == nil {
+		err = bson.Unmarshal(b, &c)
+	}
+	if err != nil || !bson.IsObjectIdHex(c.Id) {
+		return nil, ErrInvalidCursor
+	}
+	op := "$gt"
+	if q.Descending {
+		op = "$lt"
+	}
+	id := bson.ObjectIdHex(c.Id)
+	if q.Sort == "_id" {
+		return bson.M{"_id": bson.M{op: id}}, nil
+	}
+	return bson.M{"$or": []bson.M{
+		bson.M{q.Sort: bson.M{op: c.Value}},
+		bson.M{q.Sort: c.Value, "_id": bson.M{op: id}},
+	}}, nil
+}
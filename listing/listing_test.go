@@ -0,0 +1,92 @@
+package listing
+
+import (
+	"gopkg.in/mgo.v2/bson"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCursor(t *testing.T) {
+	id := bson.NewObjectId()
+	// Dates are decoded in the local time zone.
+	created := time.Date(2016, 3, 1, 12, 30, 0, 0, time.UTC).Local()
+	tests := []struct {
+		name  string
+		query Query
+		doc   bson.M
+		after bson.M
+	}{
+		{
+			name:  "id",
+			query: Query{Sort: "_id"},
+			doc:   bson.M{"_id": id, "title": "Home"},
+			after: bson.M{"_id": bson.M{"$gt": id}},
+		},
+		{
+			name:  "id descending",
+			query: Query{Sort: "_id", Descending: true},
+			doc:   bson.M{"_id": id},
+			after: bson.M{"_id": bson.M{"$lt": id}},
+		},
+		{
+			name:  "text",
+			query: Query{Sort: "title"},
+			doc:   bson.M{"_id": id, "title": "Home"},
+			after: bson.M{"$or": []bson.M{
+				bson.M{"title": bson.M{"$gt": "Home"}},
+				bson.M{"title": "Home", "_id": bson.M{"$gt": id}},
+			}},
+		},
+		{
+			name:  "date descending",
+			query: Query{Sort: "created", Descending: true},
+			doc:   bson.M{"_id": id, "created": created},
+			after: bson.M{"$or": []bson.M{
+				bson.M{"created": bson.M{"$lt": created}},
+				bson.M{"created": created, "_id": bson.M{"$lt": id}},
+			}},
+		},
+		{
+			name:  "number",
+			query: Query{Sort: "weight"},
+			doc:   bson.M{"_id": id, "weight": 3},
+			after: bson.M{"$or": []bson.M{
+				bson.M{"weight": bson.M{"$gt": 3}},
+				bson.M{"weight": 3, "_id": bson.M{"$gt": id}},
+			}},
+		},
+	}
+	for _, test := range tests {
+		b, err := bson.Marshal(test.doc)
+		if err != nil {
+			t.Fatalf("%s: %s", test.name, err.Error())
+		}
+		next, err := test.query.next(bson.Raw{Kind: 3, Data: b})
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err.Error())
+			continue
+		}
+		q := test.query
+		q.Cursor = next
+		after, err := q.after()
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err.Error())
+			continue
+		}
+		if !reflect.DeepEqual(after, test.after) {
+			t.Errorf("%s: after is %v, expected %v", test.name, after, test.after)
+		}
+	}
+}
+
+func TestInvalidCursor(t *testing.T) {
+	b, _ := bson.Marshal(cursor{Id: "home"})
+	for _, c := range []string{"not a cursor", "bm90IGJzb24=", string(b)} {
+		q := Query{Sort: "_id", Cursor: c}
+		_, err := q.after()
+		if err != ErrInvalidCursor {
+			t.Errorf("%q: error is %v, expected %v", c, err, ErrInvalidCursor)
+		}
+	}
+}
@@ -7,8 +7,9 @@ import (
 	"path/filepath"
 )
 
-// Wrapper for builidng logrus logger
-func New(f string) *logrus.Logger {
+// Wrapper for builidng logrus logger, format is "json" or "logfmt" which is
+// the default.
+func New(f string, format string) *logrus.Logger {
 	d := filepath.Dir(f)
 	_, err := os.Stat(d)
 	if err != nil {
@@ -27,5 +28,10 @@ func New(f string) *logrus.Logger {
 	}
 	var l = logrus.New()
 	l.Out = fi
+	if format == "json" {
+		l.Formatter = &logrus.JSONFormatter{}
+	} else {
+		l.Formatter = &logrus.TextFormatter{DisableColors: true}
+	}
 	return l
 }
@@ -0,0 +1,180 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/mongolar/mongolar/configs"
+	"github.com/mongolar/mongolar/wrapper"
+	"gopkg.in/mgo.v2/bson"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"strconv"
+	"strings"
+)
+
+// A variant size, images are scaled down to fit within it keeping their
+// aspect ratio.  A zero width or height is unconstrained.
+type Size struct {
+	Width  int
+	Height int
+}
+
+// Sizes used when the site does not set MediaSizes
+var DefaultSizes = map[string]string{
+	"thumbnail": "150x150",
+	"medium":    "600x0",
+	"large":     "1200x0",
+}
+
+// The sizes configured for a site.
+// Sizes are given as "<width>x<height>", invalid sizes are ignored.
+func Sizes(s *configs.SiteConfig) map[string]Size {
+	raw := s.MediaSizes
+	if len(raw) == 0 {
+		raw = DefaultSizes
+	}
+	sizes := make(map[string]Size)
+	for name, v := range raw {
+		parts := strings.Split(v, "x")
+		if len(parts) != 2 {
+			continue
+		}
+		width, werr := strconv.Atoi(parts[0])
+		height, herr := strconv.Atoi(parts[1])
+		if werr != nil || herr != nil || width < 0 || height < 0 || width+height == 0 {
+			continue
+		}
+		sizes[name] = Size{Width: width, Height: height}
+	}
+	return sizes
+}
+
+// The dimensions of an image scaled to fit, images are never scaled up.
+func (sz Size) fit(width int, height int) (int, int) {
+	if width == 0 || height == 0 {
+		return width, height
+	}
+	scale := 1.0
+	if sz.Width > 0 && width > sz.Width {
+		scale = float64(sz.Width) / float64(width)
+	}
+	if sz.Height > 0 && float64(height)*scale > float64(sz.Height) {
+		scale = float64(sz.Height) / float64(height)
+	}
+	w := int(float64(width)*scale + 0.5)
+	h := int(float64(height)*scale + 0.5)
+	if w < 1 {
+		w = 1
+	}
+	if h < 1 {
+		h = 1
+	}
+	return w, h
+}
+
+// Get the file for media in a size with its content type.
+// Variants are generated on the first request and cached in the blob store.
+func File(m Media, size string, w *wrapper.Wrapper) ([]byte, string, error) {
+	s, err := Store(w.SiteConfig)
+	if err != nil {
+		return nil, "", err
+	}
+	sz, ok := Sizes(w.SiteConfig)[size]
+	if size == Original || !m.IsImage() {
+		b, err := s.Get(m.File)
+		return b, m.ContentType, err
+	}
+	if !ok {
+		return nil, "", errors.New("Unknown size " + size)
+	}
+	contenttype := "image/png"
+	if m.ContentType == "image/jpeg" {
+		contenttype = "image/jpeg"
+	}
+	name := fmt.Sprintf("variants/%s_%s.%s", m.MongoId.Hex(), size, strings.TrimPrefix(contenttype, "image/"))
+	b, err := s.Get(name)
+	if err == nil {
+		return b, contenttype, nil
+	}
+	if err != ErrNotFound {
+		return nil, "", err
+	}
+	original, err := s.Get(m.File)
+	if err != nil {
+		return nil, "", err
+	}
+	b, err = resize(original, sz, MaxPixels(w.SiteConfig))
+	if err != nil {
+		return nil, "", err
+	}
+	err = put(s, name, b)
+	if err != nil {
+		return nil, "", err
+	}
+	c := w.DbSession.DB("").C("media")
+	err = c.UpdateId(m.MongoId, bson.M{"$addToSet": bson.M{"variants": name}})
+	return b, contenttype, err
+}
+
+// Scale an encoded image to fit a size and encode it again, jpegs stay jpegs
+// and other formats become pngs.  Images over maxpixels are not decoded.
+func resize(b []byte, sz Size, maxpixels int) ([]byte, error) {
+	c, _, err := image.DecodeConfig(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	if int64(c.Width)*int64(c.Height) > int64(maxpixels) {
+		return nil, fmt.Errorf("Image is larger than %d pixels", maxpixels)
+	}
+	src, format, err := image.Decode(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	bounds := src.Bounds()
+	width, height := sz.fit(bounds.Dx(), bounds.Dy())
+	dst := scale(src, width, height)
+	var out bytes.Buffer
+	if format == "jpeg" {
+		err = jpeg.Encode(&out, dst, &jpeg.Options{Quality: 85})
+	} else {
+		err = png.Encode(&out, dst)
+	}
+	return out.Bytes(), err
+}
+
+// Scale down by averaging the source pixels covered by each destination pixel.
+func scale(src image.Image, width int, height int) image.Image {
+	sb := src.Bounds()
+	dst := image.NewRGBA64(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := sb.Min.Y + y*sb.Dy()/height
+		y1 := sb.Min.Y + (y+1)*sb.Dy()/height
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < width; x++ {
+			x0 := sb.Min.X + x*sb.Dx()/width
+			x1 := sb.Min.X + (x+1)*sb.Dx()/width
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := src.At(sx, sy).RGBA()
+					r += uint64(pr)
+					g += uint64(pg)
+					b += uint64(pb)
+					a += uint64(pa)
+					n++
+				}
+			}
+			dst.SetRGBA64(x, y, color.RGBA64{uint16(r / n), uint16(g / n), uint16(b / n), uint16(a / n)})
+		}
+	}
+	return dst
+}
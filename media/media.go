@@ -0,0 +1,183 @@
+// Media is the library of uploaded files.  Metadata is kept in the media
+// collection and the files in the site's blob store.
+// Images can be served in the sizes from the MediaSizes site configuration,
+// each size is generated the first time it is requested and kept in the store.
+
+package media
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/mongolar/mongolar/configs"
+	"github.com/mongolar/mongolar/listing"
+	"github.com/mongolar/mongolar/wrapper"
+	"gopkg.in/mgo.v2/bson"
+	"image"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// The largest file that can be uploaded
+const MaxUpload = 32 << 20
+
+// Size name for the file as it was uploaded
+const Original = "original"
+
+// The largest image in pixels when the site does not set MediaMaxPixels.
+// Images are decoded whole to make variants, so this bounds their memory.
+const DefaultMaxPixels = 40000000
+
+// Uploaded file metadata
+type Media struct {
+	MongoId     bson.ObjectId `bson:"_id" json:"id"`
+	Name        string        `bson:"name" json:"name"`
+	File        string        `bson:"file" json:"file"`
+	ContentType string        `bson:"content_type" json:"content_type"`
+	Size        int           `bson:"size" json:"size"`
+	Width       int           `bson:"width,omitempty" json:"width,omitempty"`
+	Height      int           `bson:"height,omitempty" json:"height,omitempty"`
+	Title       string        `bson:"title" json:"title"`
+	Alt         string        `bson:"alt" json:"alt"`
+	Variants    []string      `bson:"variants,omitempty" json:"-"`
+	Uploaded    time.Time     `bson:"uploaded" json:"uploaded"`
+}
+
+// Sorts and filters available when listing media
+var Listing = listing.Definition{
+	Sorts: map[string]string{
+		"title":    "title",
+		"name":     "name",
+		"uploaded": "uploaded",
+	},
+	Filters: map[string]listing.Filter{
+		"title":        listing.Filter{Field: "title", Partial: true},
+		"name":         listing.Filter{Field: "name", Partial: true},
+		"content_type": listing.Filter{Field: "content_type", Partial: true},
+	},
+}
+
+// Store an uploaded file and its metadata.
+func Upload(name string, title string, alt string, b []byte, w *wrapper.Wrapper) (Media, error) {
+	m := Media{
+		MongoId:     bson.NewObjectId(),
+		Name:        path.Base(name),
+		ContentType: http.DetectContentType(b),
+		Size:        len(b),
+		Title:       title,
+		Alt:         alt,
+		Uploaded:    time.Now(),
+	}
+	if len(b) > MaxUpload {
+		return m, errors.New("File is too large")
+	}
+	if m.Title == "" {
+		m.Title = m.Name
+	}
+	if m.IsImage() {
+		c, _, err := image.DecodeConfig(bytes.NewReader(b))
+		if err != nil {
+			return m, err
+		}
+		m.Width = c.Width
+		m.Height = c.Height
+		if int64(m.Width)*int64(m.Height) > int64(MaxPixels(w.SiteConfig)) {
+			return m, fmt.Errorf("Image is larger than %d pixels", MaxPixels(w.SiteConfig))
+		}
+	}
+	m.File = "files/" + m.MongoId.Hex() + strings.ToLower(path.Ext(m.Name))
+	s, err := Store(w.SiteConfig)
+	if err != nil {
+		return m, err
+	}
+	err = put(s, m.File, b)
+	if err != nil {
+		return m, err
+	}
+	err = w.DbSession.DB("").C("media").Insert(m)
+	if err != nil {
+		s.Delete(m.File)
+	}
+	return m, err
+}
+
+// Get media by id
+func Load(id string, w *wrapper.Wrapper) (Media, error) {
+	var m Media
+	if !bson.IsObjectIdHex(id) {
+		return m, errors.New("Invalid Id Hex")
+	}
+	err := w.DbSession.DB("").C("media").FindId(bson.ObjectIdHex(id)).One(&m)
+	return m, err
+}
+
+// Get one page of media
+func MediaPage(q listing.Query, w *wrapper.Wrapper) ([]Media, listing.Page, error) {
+	ml := make([]Media, 0)
+	c := w.DbSession.DB("").C("media")
+	p, err := q.Run(c, &ml)
+	return ml, p, err
+}
+
+// Get all images, used for the image element editor
+func Images(w *wrapper.Wrapper) ([]Media, error) {
+	ml := make([]Media, 0)
+	c := w.DbSession.DB("").C("media")
+	err := c.Find(bson.M{"content_type": bson.M{"$in": imageTypes}}).Sort("title").All(&ml)
+	return ml, err
+}
+
+// Delete media, its file and all generated variants.
+func Delete(id string, w *wrapper.Wrapper) error {
+	m, err := Load(id, w)
+	if err != nil {
+		return err
+	}
+	s, err := Store(w.SiteConfig)
+	if err != nil {
+		return err
+	}
+	for _, v := range append(m.Variants, m.File) {
+		err = s.Delete(v)
+		if err != nil {
+			return err
+		}
+	}
+	return w.DbSession.DB("").C("media").RemoveId(m.MongoId)
+}
+
+// Content types that can be resized
+var imageTypes = []string{"image/jpeg", "image/png", "image/gif"}
+
+// The largest image a site accepts in pixels.
+func MaxPixels(s *configs.SiteConfig) int {
+	if s.MediaMaxPixels > 0 {
+		return s.MediaMaxPixels
+	}
+	return DefaultMaxPixels
+}
+
+func (m *Media) IsImage() bool {
+	for _, t := range imageTypes {
+		if m.ContentType == t {
+			return true
+		}
+	}
+	return false
+}
+
+// The url the media is served from in a size.
+func (m *Media) URL(size string, w *wrapper.Wrapper) string {
+	return fmt.Sprintf("/%s/media_file/%s/%s", w.SiteConfig.APIEndPoint, m.MongoId.Hex(), size)
+}
+
+// The dimensions of the media in a size.
+func (m *Media) Dimensions(size string, s *configs.SiteConfig) (int, int) {
+	sz, ok := Sizes(s)[size]
+	if !ok || !m.IsImage() {
+		return m.Width, m.Height
+	}
+	return sz.fit(m.Width, m.Height)
+}
@@ -0,0 +1,107 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"github.com/mongolar/mongolar/configs"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+var ErrNotFound = errors.New("Blob not found")
+
+// A blob store keeps the uploaded files and generated variants.
+// Names are relative paths made by the media package, e.g. "variants/<id>_thumbnail.jpg".
+type BlobStore interface {
+	Put(name string, r io.Reader) error
+	Get(name string) ([]byte, error)
+	Delete(name string) error
+}
+
+// Builds a blob store for a site
+type StoreConstructor func(*configs.SiteConfig) (BlobStore, error)
+
+// Blob stores by the name used in the MediaStore site configuration.
+// Other stores can be added before serving.
+var Stores = map[string]StoreConstructor{
+	"file": NewFileStore,
+}
+
+// The blob store configured for a site, the file store is the default.
+func Store(s *configs.SiteConfig) (BlobStore, error) {
+	name := s.MediaStore
+	if name == "" {
+		name = "file"
+	}
+	c, ok := Stores[name]
+	if !ok {
+		return nil, errors.New("Unknown media store " + name)
+	}
+	return c(s)
+}
+
+// Stores blobs as files under a directory.
+type FileStore struct {
+	Root string
+}
+
+// Constructor for the file store, files are kept in the site's MediaDirectory
+// or in "media" under the site directory.
+func NewFileStore(s *configs.SiteConfig) (BlobStore, error) {
+	root := s.MediaDirectory
+	if root == "" {
+		root = filepath.Join(s.Directory, "media")
+	}
+	return &FileStore{Root: root}, nil
+}
+
+// Write a blob, it is written to a temporary file first so readers never see
+// a partial file.
+func (fs *FileStore) Put(name string, r io.Reader) error {
+	f := filepath.Join(fs.Root, filepath.FromSlash(name))
+	err := os.MkdirAll(filepath.Dir(f), 0755)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(f), ".upload")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(tmp, r)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	err = os.Chmod(tmp.Name(), 0644)
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), f)
+}
+
+func (fs *FileStore) Get(name string) ([]byte, error) {
+	b, err := ioutil.ReadFile(filepath.Join(fs.Root, filepath.FromSlash(name)))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return b, err
+}
+
+func (fs *FileStore) Delete(name string) error {
+	err := os.Remove(filepath.Join(fs.Root, filepath.FromSlash(name)))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// Helper for stores to put a byte slice.
+func put(s BlobStore, name string, b []byte) error {
+	return s.Put(name, bytes.NewReader(b))
+}
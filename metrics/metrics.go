@@ -0,0 +1,212 @@
+// Metrics counts what every site in the process serves and how long it takes.
+// Counters and histograms are kept by label values and written in the
+// Prometheus text format by Handler, which is served on the server AdminPort.
+
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Histogram buckets in seconds, from 1ms to 10s.
+var DefaultBuckets = []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+var (
+	Requests           = NewCounter("mongolar_http_requests_total", "Requests served by site, controller and status.", "site", "controller", "status")
+	ControllerDuration = NewHistogram("mongolar_controller_duration_seconds", "Time spent in api controllers.", DefaultBuckets, "site", "controller")
+	DBDuration         = NewHistogram("mongolar_mongodb_request_path_duration_seconds", "Time spent in the MongoDB queries every page load runs: sessions, form_register, elements, routes and redirects.", DefaultBuckets, "site", "operation")
+	Sessions           = NewCounter("mongolar_sessions_created_total", "Sessions created.", "site")
+	CacheHits          = NewCounter("mongolar_element_cache_hits_total", "Elements served from the element cache.", "site")
+	CacheMisses        = NewCounter("mongolar_element_cache_misses_total", "Elements the element cache had to load.", "site")
+	FormFailures       = NewCounter("mongolar_form_validation_failures_total", "Form submissions rejected by reason.", "site", "reason")
+)
+
+// Everything written by Handler, in the order it was created.
+var registry = struct {
+	sync.Mutex
+	metrics []metric
+}{}
+
+type metric interface {
+	write(w *bufio.Writer)
+}
+
+func register(m metric) {
+	registry.Lock()
+	registry.metrics = append(registry.metrics, m)
+	registry.Unlock()
+}
+
+// A value that only goes up, kept by label values.
+type Counter struct {
+	sync.Mutex
+	name   string
+	help   string
+	labels []string
+	values map[string]float64
+}
+
+// Create and register a counter with the names of its labels.
+func NewCounter(name string, help string, labels ...string) *Counter {
+	c := &Counter{name: name, help: help, labels: labels, values: make(map[string]float64)}
+	register(c)
+	return c
+}
+
+// Add one, values are given in the order of the labels.
+func (c *Counter) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+// Add to the counter, values are given in the order of the labels.
+func (c *Counter) Add(v float64, values ...string) {
+	k := key(values)
+	c.Lock()
+	c.values[k] += v
+	c.Unlock()
+}
+
+func (c *Counter) write(w *bufio.Writer) {
+	c.Lock()
+	defer c.Unlock()
+	header(w, c.name, c.help, "counter")
+	for _, k := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, labels(c.labels, k, ""), number(c.values[k]))
+	}
+}
+
+// Observations counted in buckets, kept by label values.
+type Histogram struct {
+	sync.Mutex
+	name    string
+	help    string
+	labels  []string
+	buckets []float64
+	values  map[string]*histogramValue
+}
+
+type histogramValue struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// Create and register a histogram with its buckets and the names of its labels.
+func NewHistogram(name string, help string, buckets []float64, labels ...string) *Histogram {
+	h := &Histogram{name: name, help: help, labels: labels, buckets: buckets, values: make(map[string]*histogramValue)}
+	register(h)
+	return h
+}
+
+// Observe a value, values are given in the order of the labels.
+func (h *Histogram) Observe(v float64, values ...string) {
+	k := key(values)
+	h.Lock()
+	hv, ok := h.values[k]
+	if !ok {
+		hv = &histogramValue{counts: make([]uint64, len(h.buckets))}
+		h.values[k] = hv
+	}
+	for i, b := range h.buckets {
+		if v <= b {
+			hv.counts[i]++
+		}
+	}
+	hv.count++
+	hv.sum += v
+	h.Unlock()
+}
+
+// Observe the seconds since start.
+func (h *Histogram) Since(start time.Time, values ...string) {
+	h.Observe(time.Since(start).Seconds(), values...)
+}
+
+func (h *Histogram) write(w *bufio.Writer) {
+	h.Lock()
+	defer h.Unlock()
+	header(w, h.name, h.help, "histogram")
+	keys := make([]string, 0, len(h.values))
+	for k := range h.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		hv := h.values[k]
+		for i, b := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, labels(h.labels, k, number(b)), hv.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, labels(h.labels, k, "+Inf"), hv.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, labels(h.labels, k, ""), number(hv.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, labels(h.labels, k, ""), hv.count)
+	}
+}
+
+// Serve every metric in the Prometheus text format.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		bw := bufio.NewWriter(w)
+		registry.Lock()
+		for _, m := range registry.metrics {
+			m.write(bw)
+		}
+		registry.Unlock()
+		bw.Flush()
+	})
+}
+
+// Escapes label values the way the text format expects.
+var escaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// Label values are joined with a byte that can not be in a label value.
+func key(values []string) string {
+	return strings.Join(values, "\xff")
+}
+
+func sortedKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func header(w *bufio.Writer, name string, help string, t string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, t)
+}
+
+// Format label names and the values in key as {name="value",...}, le is
+// added for histogram buckets when it is not empty.
+func labels(names []string, k string, le string) string {
+	pairs := make([]string, 0, len(names)+1)
+	if len(names) > 0 {
+		values := strings.Split(k, "\xff")
+		for i, name := range names {
+			v := ""
+			if i < len(values) {
+				v = values[i]
+			}
+			pairs = append(pairs, name+`="`+escaper.Replace(v)+`"`)
+		}
+	}
+	if le != "" {
+		pairs = append(pairs, `le="`+le+`"`)
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func number(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
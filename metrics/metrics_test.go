@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"bufio"
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func output(m metric) string {
+	var b bytes.Buffer
+	w := bufio.NewWriter(&b)
+	m.write(w)
+	w.Flush()
+	return b.String()
+}
+
+func TestCounter(t *testing.T) {
+	c := &Counter{name: "test_total", help: "Test counter.", labels: []string{"site", "status"}, values: make(map[string]float64)}
+	c.Inc("b", "200")
+	c.Inc("a", "500")
+	c.Add(2.5, "a", "500")
+	c.Inc("a", `say "hi"`+"\n")
+	expected := `# HELP test_total Test counter.
+# TYPE test_total counter
+test_total{site="a",status="500"} 3.5
+test_total{site="a",status="say \"hi\"\n"} 1
+test_total{site="b",status="200"} 1
+`
+	if out := output(c); out != expected {
+		t.Errorf("counter output is\n%s\nexpected\n%s", out, expected)
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	h := &Histogram{name: "test_seconds", help: "Test histogram.", buckets: []float64{.1, 1}, labels: []string{"site"}, values: make(map[string]*histogramValue)}
+	h.Observe(.05, "a")
+	h.Observe(.5, "a")
+	h.Observe(2, "a")
+	expected := `# HELP test_seconds Test histogram.
+# TYPE test_seconds histogram
+test_seconds_bucket{site="a",le="0.1"} 1
+test_seconds_bucket{site="a",le="1"} 2
+test_seconds_bucket{site="a",le="+Inf"} 3
+test_seconds_sum{site="a"} 2.55
+test_seconds_count{site="a"} 3
+`
+	if out := output(h); out != expected {
+		t.Errorf("histogram output is\n%s\nexpected\n%s", out, expected)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	c := NewCounter("test_handler_total", "Counter without labels.")
+	c.Inc()
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); ct != "text/plain; version=0.0.4" {
+		t.Errorf("content type is %s", ct)
+	}
+	body := rec.Body.String()
+	for _, line := range []string{"# TYPE mongolar_http_requests_total counter", "# TYPE test_handler_total counter", "test_handler_total 1\n"} {
+		if !strings.Contains(body, line) {
+			t.Errorf("output does not contain %q", line)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"fmt"
+	"github.com/Sirupsen/logrus"
+	"github.com/mongolar/mongolar/configs"
+	"github.com/mongolar/mongolar/controller"
+	"github.com/mongolar/mongolar/metrics"
+	"github.com/mongolar/mongolar/services"
+	"github.com/mongolar/mongolar/user"
+	"github.com/mongolar/mongolar/wrapper"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"time"
+)
+
+// Log every request to a site with what was served, who it was served to
+// and how long it took.
+func AccessLog(s *configs.SiteConfig, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &wrapper.ResponseRecorder{ResponseWriter: w}
+		l := wrapper.RequestLogger(s, rec, r)
+		next.ServeHTTP(rec, r)
+		if rec.Status == 0 {
+			rec.Status = http.StatusOK
+		}
+		metrics.Requests.Inc(s.Name, rec.Controller, strconv.Itoa(rec.Status))
+		l.WithFields(logrus.Fields{
+			"method":     r.Method,
+			"path":       r.URL.Path,
+			"controller": rec.Controller,
+			"status":     rec.Status,
+			"bytes":      rec.Bytes,
+			"latency":    time.Since(start).Seconds(),
+			"user_id":    rec.UserId,
+			"remote":     r.RemoteAddr,
+		}).Info("access")
+	})
+}
+
+// Log how long each request to a site takes.
+func TimingHTTP(s *configs.SiteConfig, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		next.ServeHTTP(w, r)
+		logmessage := fmt.Sprintf("%s %s took %s", r.Method, r.URL.Path, time.Since(start))
+		wrapper.RequestLogger(s, w, r).Info(logmessage)
+	})
+}
+
+// Answer requests that panic with a 500 and log the panic with the request
+// and the stack.
+func RecoverHTTP(s *configs.SiteConfig, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if p := recover(); p != nil {
+				errmessage := fmt.Sprintf("Panic serving %s %s%s from %s : %v\n%s",
+					r.Method, r.Host, r.URL.Path, r.RemoteAddr, p, debug.Stack())
+				wrapper.RequestLogger(s, w, r).Error(errmessage)
+				wrapper.WriteError(w, r, 500, "Internal Server Error")
+			}
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Log how long each controller takes.
+func Timing(c func(*wrapper.Wrapper)) func(*wrapper.Wrapper) {
+	return func(w *wrapper.Wrapper) {
+		start := time.Now()
+		c(w)
+		logmessage := fmt.Sprintf("Controller %s took %s", w.Request.URL.Path, time.Since(start))
+		w.Logger.Info(logmessage)
+	}
+}
+
+// Answer controllers that panic with a 500, log the panic and close the
+// database session the controller never served.
+func Recover(c func(*wrapper.Wrapper)) func(*wrapper.Wrapper) {
+	return func(w *wrapper.Wrapper) {
+		defer func() {
+			if p := recover(); p != nil {
+				r := w.Request
+				errmessage := fmt.Sprintf("Panic in controller serving %s %s%s from %s params %v : %v\n%s",
+					r.Method, r.Host, r.URL.Path, r.RemoteAddr, w.APIParams, p, debug.Stack())
+				w.Logger.Error(errmessage)
+				w.Error(500, "Internal Server Error")
+			}
+		}()
+		c(w)
+	}
+}
+
+// Stop browsers and proxies from caching a controller's responses.
+func NoCache(c func(*wrapper.Wrapper)) func(*wrapper.Wrapper) {
+	return func(w *wrapper.Wrapper) {
+		w.Writer.Header().Add("Cache-Control", "no-cache, no-store, must-revalidate")
+		w.Writer.Header().Add("Pragma", "no-cache")
+		w.Writer.Header().Add("Expires", "0")
+		c(w)
+	}
+}
+
+// Only run a controller for users with a role, other users are sent to the
+// site's login or access denied url from LoginURLs.
+func RequireRole(role string) controller.Middleware {
+	return func(c func(*wrapper.Wrapper)) func(*wrapper.Wrapper) {
+		return func(w *wrapper.Wrapper) {
+			u := new(user.User)
+			err := u.Get(w)
+			loginurls := make(map[string]string)
+			w.SiteConfig.RawConfig.MarshalKey("LoginURLs", &loginurls)
+			if err != nil {
+				services.Redirect(loginurls["login"], w)
+				w.Serve()
+				return
+			}
+			for _, r := range u.Roles {
+				if r == role {
+					c(w)
+					return
+				}
+			}
+			services.Redirect(loginurls["access_denied"], w)
+			w.Serve()
+		}
+	}
+}
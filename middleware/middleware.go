@@ -0,0 +1,59 @@
+// Middleware composes cross cutting behavior around requests at two levels.
+// HTTP middleware wraps the handler for a whole site, controller middleware
+// wraps every api controller.  Sites choose middleware by name with
+// HTTPMiddleware and ControllerMiddleware, in the order it runs.  Recovery
+// from panics is always outermost at both levels, with the access log
+// outside of it for HTTP.
+
+package middleware
+
+import (
+	"fmt"
+	"github.com/mongolar/mongolar/configs"
+	"github.com/mongolar/mongolar/controller"
+	"net/http"
+)
+
+// HTTP middleware gets the site so it can use its configuration and logger.
+type HTTP func(s *configs.SiteConfig, next http.Handler) http.Handler
+
+// HTTP middleware by name, add to this before the router is built.
+var HTTPMap = map[string]HTTP{
+	"timing": TimingHTTP,
+}
+
+// Controller middleware by name, add to this before the router is built.
+var ControllerMap = map[string]controller.Middleware{
+	"timing":  Timing,
+	"nocache": NoCache,
+}
+
+// Wrap a site's handler in its HTTP middleware.
+func SiteHandler(s *configs.SiteConfig, h http.Handler) http.Handler {
+	for i := len(s.HTTPMiddleware) - 1; i >= 0; i-- {
+		name := s.HTTPMiddleware[i]
+		m, ok := HTTPMap[name]
+		if !ok {
+			errmessage := fmt.Sprintf("Unknown http middleware %s", name)
+			s.Logger.Error(errmessage)
+			continue
+		}
+		h = m(s, h)
+	}
+	return AccessLog(s, RecoverHTTP(s, h))
+}
+
+// The controller middleware of a site.
+func Controllers(s *configs.SiteConfig) []controller.Middleware {
+	ml := []controller.Middleware{Recover}
+	for _, name := range s.ControllerMiddleware {
+		m, ok := ControllerMap[name]
+		if !ok {
+			errmessage := fmt.Sprintf("Unknown controller middleware %s", name)
+			s.Logger.Error(errmessage)
+			continue
+		}
+		ml = append(ml, m)
+	}
+	return ml
+}
@@ -2,7 +2,10 @@ package contenttypes
 
 import (
 	"errors"
+	"fmt"
 	"github.com/mongolar/mongolar/form"
+	"github.com/mongolar/mongolar/listing"
+	"github.com/mongolar/mongolar/models/elements"
 	"github.com/mongolar/mongolar/wrapper"
 	"gopkg.in/mgo.v2/bson"
 )
@@ -41,7 +44,7 @@ func (ct *ContentType) Save(w *wrapper.Wrapper) error {
 		return errors.New("Type required")
 	}
 	c := w.DbSession.DB("").C("content_types")
-	_, err := c.Upsert(ct.MongoId, ct)
+	_, err := c.Upsert(bson.M{"_id": ct.MongoId}, ct)
 	if err != nil {
 		return err
 	}
@@ -63,13 +66,115 @@ func (ct *ContentType) GetByType(t string, w *wrapper.Wrapper) error {
 	return err
 }
 
+// Validate and coerce content values against the content type form.
+// Unknown keys are stripped, numbers and booleans coerced and required fields enforced.
+func (ct *ContentType) Validate(data map[string]interface{}) (map[string]interface{}, map[string]string) {
+	return form.CoerceData(ct.Form, data)
+}
+
+// Get the element ids referenced by content values keyed by field path.
+func (ct *ContentType) References(data map[string]interface{}) map[string][]string {
+	refs := make(map[string][]string)
+	form.MapReferences(ct.Form, data, func(p string, f *form.Field, ids []string) interface{} {
+		refs[p] = ids
+		return nil
+	})
+	return refs
+}
+
+// Check that referenced elements exist and are of the content type the field allows.
+func (ct *ContentType) ValidateReferences(data map[string]interface{}, w *wrapper.Wrapper) map[string]string {
+	invalid := make(map[string]string)
+	// Elements that can not be loaded are reported as missing.
+	loaded, err := elements.LoadContentElements(referencedIds(ct.References(data)), w)
+	if err != nil {
+		errmessage := fmt.Sprintf("Unable to load referenced elements : %s", err.Error())
+		w.Logger.Error(errmessage)
+	}
+	form.MapReferences(ct.Form, data, func(p string, f *form.Field, ids []string) interface{} {
+		allowed := ""
+		if f.TemplateOptions != nil {
+			allowed = f.TemplateOptions.ContentType
+		}
+		for _, id := range ids {
+			e, ok := loaded[id]
+			if !ok {
+				invalid[p] = fmt.Sprintf("%s references an element that does not exist.", f.Label())
+				continue
+			}
+			if allowed != "" && allowed != e.ContentValues.Type {
+				invalid[p] = fmt.Sprintf("%s must reference %s content.", f.Label(), allowed)
+			}
+		}
+		return nil
+	})
+	return invalid
+}
+
+// Replace the ids in reference fields with the referenced elements, loaded
+// with one query.  Elements that can not be loaded are left out.
+func (ct *ContentType) Expand(data map[string]interface{}, w *wrapper.Wrapper) map[string]interface{} {
+	loaded, err := elements.LoadContentElements(referencedIds(ct.References(data)), w)
+	if err != nil {
+		errmessage := fmt.Sprintf("Unable to load referenced elements : %s", err.Error())
+		w.Logger.Error(errmessage)
+	}
+	return form.MapReferences(ct.Form, data, func(p string, f *form.Field, ids []string) interface{} {
+		expanded := make([]map[string]interface{}, 0)
+		for _, id := range ids {
+			e, ok := loaded[id]
+			if !ok {
+				continue
+			}
+			expanded = append(expanded, map[string]interface{}{
+				"mongolarid":       e.MongoId.Hex(),
+				"mongolartemplate": e.Template,
+				"title":            e.Title,
+				"content":          e.ContentValues.Content,
+			})
+		}
+		if f.TemplateOptions != nil && f.TemplateOptions.Multiple {
+			return expanded
+		}
+		if len(expanded) == 0 {
+			return nil
+		}
+		return expanded[0]
+	})
+}
+
+// Every id in a set of references, once.
+func referencedIds(refs map[string][]string) []string {
+	ids := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, rids := range refs {
+		for _, id := range rids {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
 func AllContentTypes(w *wrapper.Wrapper) ([]ContentType, error) {
 	cl := make([]ContentType, 0)
 	c := w.DbSession.DB("").C("content_types")
-	i := c.Find(nil).Limit(50).Iter()
+	i := c.Find(nil).Iter()
 	err := i.All(&cl)
 	if err != nil {
 		return nil, err
 	}
 	return cl, nil
 }
+
+// Sorts and filters available when listing content types
+var Listing = listing.Definition{
+	Sorts: map[string]string{
+		"type": "type",
+	},
+	Filters: map[string]listing.Filter{
+		"type": listing.Filter{Field: "type", Partial: true},
+	},
+}
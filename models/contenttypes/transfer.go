@@ -0,0 +1,234 @@
+// Content types can be exported to and imported from YAML or JSON files so they
+// can be versioned and promoted between sites.
+
+package contenttypes
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/mongolar/mongolar/form"
+	"github.com/mongolar/mongolar/wrapper"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/yaml.v2"
+)
+
+// Import modes.  Diff reports the changes an import would make without saving
+// anything, merge adds new content types and fields, updates fields with the
+// same key and keeps fields that are not in the import, replace overwrites
+// content types with the imported definition.
+const (
+	ImportDiff    = "diff"
+	ImportMerge   = "merge"
+	ImportReplace = "replace"
+)
+
+// Structure of an export file
+type Export struct {
+	ContentTypes []ContentType `json:"content_types"`
+}
+
+// The changes an import made, or would make, to a single content type.
+type Change struct {
+	Type    string   `json:"type"`
+	Action  string   `json:"action"`
+	Added   []string `json:"added,omitempty"`
+	Changed []string `json:"changed,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// Export all content types in the given format, "json" or "yaml".
+// Ids are left out, content types are matched by type on import.
+func ExportContentTypes(format string, w *wrapper.Wrapper) ([]byte, error) {
+	cts, err := AllContentTypes(w)
+	if err != nil {
+		return nil, err
+	}
+	for i := range cts {
+		cts[i].MongoId = ""
+	}
+	return Encode(Export{ContentTypes: cts}, format)
+}
+
+// Encode a value as JSON or YAML using its json field names.
+func Encode(v interface{}, format string) ([]byte, error) {
+	js, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case "json":
+		return js, nil
+	case "yaml", "yml":
+		var generic interface{}
+		err = json.Unmarshal(js, &generic)
+		if err != nil {
+			return nil, err
+		}
+		return yaml.Marshal(generic)
+	}
+	return nil, fmt.Errorf("Unknown format %s", format)
+}
+
+// Decode JSON or YAML into a value using its json field names.
+// If format is empty it is detected from the content.
+func Decode(b []byte, format string, v interface{}) error {
+	if format == "" {
+		format = "yaml"
+		if bytes.HasPrefix(bytes.TrimSpace(b), []byte("{")) {
+			format = "json"
+		}
+	}
+	switch format {
+	case "json":
+		return json.Unmarshal(b, v)
+	case "yaml", "yml":
+		var generic interface{}
+		err := yaml.Unmarshal(b, &generic)
+		if err != nil {
+			return err
+		}
+		js, err := json.Marshal(stringKeys(generic))
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(js, v)
+	}
+	return fmt.Errorf("Unknown format %s", format)
+}
+
+// YAML decodes maps with interface keys which JSON can not encode.
+func stringKeys(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{})
+		for k, i := range t {
+			m[fmt.Sprint(k)] = stringKeys(i)
+		}
+		return m
+	case []interface{}:
+		for i := range t {
+			t[i] = stringKeys(t[i])
+		}
+	}
+	return v
+}
+
+// Import content types from an export file.
+func ImportContentTypes(b []byte, format string, mode string, w *wrapper.Wrapper) ([]Change, error) {
+	var e Export
+	err := Decode(b, format, &e)
+	if err != nil {
+		return nil, err
+	}
+	return Import(e.ContentTypes, mode, w)
+}
+
+// Import content types using one of the import modes.
+func Import(cts []ContentType, mode string, w *wrapper.Wrapper) ([]Change, error) {
+	if mode != ImportDiff && mode != ImportMerge && mode != ImportReplace {
+		return nil, fmt.Errorf("Unknown import mode %s", mode)
+	}
+	changes := make([]Change, 0)
+	for _, ct := range cts {
+		if ct.Type == "" {
+			return changes, errors.New("Type required")
+		}
+		existing, err := LoadContentTypeT(ct.Type, w)
+		if err != nil {
+			if err != mgo.ErrNotFound {
+				return changes, err
+			}
+			change := Change{Type: ct.Type, Action: "add"}
+			for _, f := range ct.Form {
+				change.Added = append(change.Added, f.Key)
+			}
+			changes = append(changes, change)
+			if mode != ImportDiff {
+				nct := NewContentType()
+				nct.Type = ct.Type
+				nct.Form = ct.Form
+				err = nct.Save(w)
+				if err != nil {
+					return changes, err
+				}
+			}
+			continue
+		}
+		change := diffFields(existing.Form, ct.Form)
+		change.Type = ct.Type
+		changes = append(changes, change)
+		if mode == ImportDiff || change.Action == "unchanged" {
+			continue
+		}
+		if mode == ImportReplace {
+			existing.Form = ct.Form
+		} else {
+			existing.Form = mergeFields(existing.Form, ct.Form)
+		}
+		err = existing.Save(w)
+		if err != nil {
+			return changes, err
+		}
+	}
+	return changes, nil
+}
+
+// Compare two field lists by key.
+func diffFields(current []*form.Field, imported []*form.Field) Change {
+	c := Change{Action: "unchanged"}
+	byKey := make(map[string]*form.Field)
+	for _, f := range current {
+		byKey[f.Key] = f
+	}
+	seen := make(map[string]bool)
+	for _, f := range imported {
+		seen[f.Key] = true
+		cf, ok := byKey[f.Key]
+		if !ok {
+			c.Added = append(c.Added, f.Key)
+		} else if !sameField(cf, f) {
+			c.Changed = append(c.Changed, f.Key)
+		}
+	}
+	for _, f := range current {
+		if !seen[f.Key] {
+			c.Removed = append(c.Removed, f.Key)
+		}
+	}
+	if len(c.Added) > 0 || len(c.Changed) > 0 || len(c.Removed) > 0 {
+		c.Action = "update"
+	}
+	return c
+}
+
+// Fields are compared by their json encoding so empty and missing values are equal.
+func sameField(a *form.Field, b *form.Field) bool {
+	ja, erra := json.Marshal(a)
+	jb, errb := json.Marshal(b)
+	return erra == nil && errb == nil && bytes.Equal(ja, jb)
+}
+
+// Fields from the import replace fields with the same key, new fields are appended.
+func mergeFields(current []*form.Field, imported []*form.Field) []*form.Field {
+	byKey := make(map[string]*form.Field)
+	for _, f := range imported {
+		byKey[f.Key] = f
+	}
+	merged := make([]*form.Field, 0)
+	for _, f := range current {
+		if nf, ok := byKey[f.Key]; ok {
+			merged = append(merged, nf)
+			delete(byKey, f.Key)
+			continue
+		}
+		merged = append(merged, f)
+	}
+	for _, f := range imported {
+		if _, ok := byKey[f.Key]; ok {
+			merged = append(merged, f)
+		}
+	}
+	return merged
+}
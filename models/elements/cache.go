@@ -0,0 +1,292 @@
+package elements
+
+import (
+	"container/list"
+	"fmt"
+	"github.com/mongolar/mongolar/configs"
+	"github.com/mongolar/mongolar/metrics"
+	"github.com/mongolar/mongolar/wrapper"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults for the ElementCacheSize and ElementCacheBytes site configuration.
+const (
+	DefaultCacheSize  = 10000
+	DefaultCacheBytes = 32 << 20
+)
+
+// The capped collection invalidations are published to so every server
+// running a site drops the same elements.
+const invalidations = "element_invalidations"
+
+// A least recently used cache of raw element documents.
+type cache struct {
+	size       int
+	maxbytes   int
+	bytes      int
+	order      *list.List
+	items      map[string]*list.Element
+	generation int
+}
+
+type cacheItem struct {
+	id  string
+	raw bson.Raw
+}
+
+// Element caches by site
+var caches = struct {
+	sync.Mutex
+	sites map[*configs.SiteConfig]*cache
+}{sites: make(map[*configs.SiteConfig]*cache)}
+
+// An invalidation, All drops every element of the site.
+type invalidation struct {
+	MongoId bson.ObjectId `bson:"_id"`
+	Ids     []string      `bson:"ids,omitempty"`
+	All     bool          `bson:"all,omitempty"`
+}
+
+// Get the cache of a site, nil if it is disabled with a negative ElementCacheSize.
+// Call with caches locked.
+func siteCache(s *configs.SiteConfig) *cache {
+	if c, ok := caches.sites[s]; ok {
+		return c
+	}
+	size := s.ElementCacheSize
+	if size == 0 {
+		size = DefaultCacheSize
+	}
+	maxbytes := s.ElementCacheBytes
+	if maxbytes == 0 {
+		maxbytes = DefaultCacheBytes
+	}
+	var c *cache
+	if size > 0 {
+		c = &cache{size: size, maxbytes: maxbytes, order: list.New(), items: make(map[string]*list.Element)}
+	}
+	caches.sites[s] = c
+	return c
+}
+
+func (c *cache) get(id string) (bson.Raw, bool) {
+	e, ok := c.items[id]
+	if !ok {
+		return bson.Raw{}, false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*cacheItem).raw, true
+}
+
+func (c *cache) put(id string, raw bson.Raw) {
+	if len(raw.Data) > c.maxbytes {
+		return
+	}
+	c.remove(id)
+	c.items[id] = c.order.PushFront(&cacheItem{id: id, raw: raw})
+	c.bytes += len(raw.Data)
+	for c.order.Len() > c.size || c.bytes > c.maxbytes {
+		c.remove(c.order.Back().Value.(*cacheItem).id)
+	}
+}
+
+func (c *cache) remove(id string) {
+	e, ok := c.items[id]
+	if !ok {
+		return
+	}
+	c.order.Remove(e)
+	delete(c.items, id)
+	c.bytes -= len(e.Value.(*cacheItem).raw.Data)
+}
+
+func (c *cache) clear() {
+	c.order.Init()
+	c.items = make(map[string]*list.Element)
+	c.bytes = 0
+}
+
+// Load raw element documents by id, from the cache where possible and with
+// one query for the rest.  Ids that are not found are left out.
+func loadRaw(ids []string, w *wrapper.Wrapper) (map[string]bson.Raw, error) {
+	found := make(map[string]bson.Raw)
+	missing := make([]bson.ObjectId, 0)
+	caches.Lock()
+	c := siteCache(w.SiteConfig)
+	generation := 0
+	if c != nil {
+		generation = c.generation
+	}
+	for _, id := range ids {
+		if !bson.IsObjectIdHex(id) {
+			continue
+		}
+		if c != nil {
+			if raw, ok := c.get(id); ok {
+				found[id] = raw
+				continue
+			}
+		}
+		missing = append(missing, bson.ObjectIdHex(id))
+	}
+	caches.Unlock()
+	if c != nil {
+		metrics.CacheHits.Add(float64(len(found)), w.SiteConfig.Name)
+		metrics.CacheMisses.Add(float64(len(missing)), w.SiteConfig.Name)
+	}
+	if len(missing) == 0 {
+		return found, nil
+	}
+	docs := make([]bson.Raw, 0)
+	q := bson.M{"_id": bson.M{"$in": missing}}
+	start := time.Now()
+	err := w.DbSession.DB("").C("elements").Find(q).All(&docs)
+	metrics.DBDuration.Since(start, w.SiteConfig.Name, "elements")
+	if err != nil {
+		return found, err
+	}
+	caches.Lock()
+	defer caches.Unlock()
+	for _, raw := range docs {
+		var d struct {
+			MongoId bson.ObjectId `bson:"_id"`
+		}
+		err = raw.Unmarshal(&d)
+		if err != nil {
+			return found, err
+		}
+		found[d.MongoId.Hex()] = raw
+		// Documents read while elements were invalidated may be stale.
+		if c != nil && c.generation == generation {
+			c.put(d.MongoId.Hex(), raw)
+		}
+	}
+	return found, nil
+}
+
+// Functions called with the ids of changed elements, all is set when any
+// element may have changed.
+type ChangeHook func(ids []string, all bool, w *wrapper.Wrapper)
+
+var changeHooks = make([]ChangeHook, 0)
+
+// Add a function to call when elements are written, hooks run on the server
+// making the change only.
+func OnChange(h ChangeHook) {
+	changeHooks = append(changeHooks, h)
+}
+
+// Drop elements from the cache of this server and publish the invalidation
+// to the other servers running the site.
+func Invalidate(w *wrapper.Wrapper, ids ...string) {
+	invalidate(w.SiteConfig, ids, false)
+	publish(w, invalidation{MongoId: bson.NewObjectId(), Ids: ids})
+	for _, h := range changeHooks {
+		h(ids, false, w)
+	}
+}
+
+// Drop every element from the cache, used after writes to many elements.
+func InvalidateAll(w *wrapper.Wrapper) {
+	invalidate(w.SiteConfig, nil, true)
+	publish(w, invalidation{MongoId: bson.NewObjectId(), All: true})
+	for _, h := range changeHooks {
+		h(nil, true, w)
+	}
+}
+
+func invalidate(s *configs.SiteConfig, ids []string, all bool) {
+	caches.Lock()
+	defer caches.Unlock()
+	c := siteCache(s)
+	if c == nil {
+		return
+	}
+	c.generation++
+	if all {
+		c.clear()
+		return
+	}
+	for _, id := range ids {
+		c.remove(id)
+	}
+}
+
+func publish(w *wrapper.Wrapper, i invalidation) {
+	err := w.DbSession.DB("").C(invalidations).Insert(i)
+	if err != nil {
+		errmessage := fmt.Sprintf("Unable to publish element invalidation: %s", err.Error())
+		w.Logger.Error(errmessage)
+	}
+}
+
+// Create the capped collection invalidations are published to, it has to
+// exist before anything is published or it can not be tailed.
+func EnsureInvalidations(s *configs.SiteConfig) error {
+	session := s.DbSession.Copy()
+	defer session.Close()
+	err := session.DB("").C(invalidations).Create(&mgo.CollectionInfo{Capped: true, MaxBytes: 1 << 20})
+	if err != nil && !strings.Contains(err.Error(), "already exists") {
+		return err
+	}
+	return nil
+}
+
+// Follow the invalidations published by other servers for a site, this
+// blocks and is run in its own goroutine by the server.
+func Listen(s *configs.SiteConfig) {
+	err := EnsureInvalidations(s)
+	if err != nil {
+		errmessage := fmt.Sprintf("Unable to create element invalidations: %s", err.Error())
+		s.Logger.Error(errmessage)
+	}
+	session := s.DbSession.Copy()
+	defer session.Close()
+	c := session.DB("").C(invalidations)
+	// Ids from different servers do not sort by time, so the position is
+	// the last invalidation read in insertion order and everything up to it
+	// is skipped when the cursor is opened again.
+	var last bson.ObjectId
+	var newest invalidation
+	if c.Find(nil).Sort("-$natural").One(&newest) == nil {
+		last = newest.MongoId
+	}
+	for {
+		seen := last == ""
+		if !seen {
+			n, err := c.FindId(last).Count()
+			if err == nil && n == 0 {
+				// The position was overwritten, some invalidations may be lost.
+				invalidate(s, nil, true)
+				seen = true
+			}
+		}
+		iter := c.Find(nil).Sort("$natural").Tail(5 * time.Second)
+		var i invalidation
+		for {
+			for iter.Next(&i) {
+				if !seen {
+					seen = i.MongoId == last
+					continue
+				}
+				last = i.MongoId
+				invalidate(s, i.Ids, i.All)
+			}
+			if iter.Err() != nil || !iter.Timeout() {
+				break
+			}
+		}
+		err = iter.Close()
+		if err != nil {
+			errmessage := fmt.Sprintf("Element invalidations interrupted: %s", err.Error())
+			s.Logger.Error(errmessage)
+			session.Refresh()
+		}
+		// A tailable cursor on an empty collection ends at once.
+		time.Sleep(time.Second)
+	}
+}
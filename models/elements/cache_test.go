@@ -0,0 +1,105 @@
+package elements
+
+import (
+	"container/list"
+	"gopkg.in/mgo.v2/bson"
+	"reflect"
+	"testing"
+)
+
+func TestCacheEviction(t *testing.T) {
+	tests := []struct {
+		name     string
+		size     int
+		maxbytes int
+		steps    []string
+		bytes    int
+		kept     []string
+	}{
+		{
+			name:     "by count",
+			size:     2,
+			maxbytes: 100,
+			steps:    []string{"put a", "put b", "put c"},
+			bytes:    20,
+			kept:     []string{"c", "b"},
+		},
+		{
+			name:     "by bytes",
+			size:     10,
+			maxbytes: 25,
+			steps:    []string{"put a", "put b", "put c"},
+			bytes:    20,
+			kept:     []string{"c", "b"},
+		},
+		{
+			name:     "recently read is kept",
+			size:     2,
+			maxbytes: 100,
+			steps:    []string{"put a", "put b", "get a", "put c"},
+			bytes:    20,
+			kept:     []string{"c", "a"},
+		},
+		{
+			name:     "replacing does not count twice",
+			size:     2,
+			maxbytes: 100,
+			steps:    []string{"put a", "put b", "put a"},
+			bytes:    20,
+			kept:     []string{"a", "b"},
+		},
+		{
+			name:     "larger than the cache is not kept",
+			size:     2,
+			maxbytes: 5,
+			steps:    []string{"put a"},
+			bytes:    0,
+			kept:     []string{},
+		},
+	}
+	for _, test := range tests {
+		c := &cache{size: test.size, maxbytes: test.maxbytes, order: list.New(), items: make(map[string]*list.Element)}
+		for _, step := range test.steps {
+			op, id := step[:3], step[4:]
+			if op == "get" {
+				c.get(id)
+				continue
+			}
+			c.put(id, bson.Raw{Kind: 3, Data: make([]byte, 10)})
+		}
+		kept := make([]string, 0)
+		for e := c.order.Front(); e != nil; e = e.Next() {
+			kept = append(kept, e.Value.(*cacheItem).id)
+		}
+		if !reflect.DeepEqual(kept, test.kept) {
+			t.Errorf("%s: kept %v, expected %v", test.name, kept, test.kept)
+		}
+		if len(c.items) != len(test.kept) {
+			t.Errorf("%s: %d items, expected %d", test.name, len(c.items), len(test.kept))
+		}
+		if c.bytes != test.bytes {
+			t.Errorf("%s: %d bytes, expected %d", test.name, c.bytes, test.bytes)
+		}
+	}
+}
+
+func TestCacheRemove(t *testing.T) {
+	c := &cache{size: 10, maxbytes: 100, order: list.New(), items: make(map[string]*list.Element)}
+	c.put("a", bson.Raw{Kind: 3, Data: make([]byte, 10)})
+	c.put("b", bson.Raw{Kind: 3, Data: make([]byte, 20)})
+	c.remove("a")
+	c.remove("missing")
+	if _, ok := c.get("a"); ok {
+		t.Errorf("a is still cached")
+	}
+	if _, ok := c.get("b"); !ok {
+		t.Errorf("b is no longer cached")
+	}
+	if c.bytes != 20 || c.order.Len() != 1 {
+		t.Errorf("%d bytes in %d items, expected 20 bytes in 1 item", c.bytes, c.order.Len())
+	}
+	c.clear()
+	if c.bytes != 0 || c.order.Len() != 0 || len(c.items) != 0 {
+		t.Errorf("%d bytes in %d items after clear", c.bytes, c.order.Len())
+	}
+}
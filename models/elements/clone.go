@@ -0,0 +1,151 @@
+package elements
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mongolar/mongolar/models/references"
+	"github.com/mongolar/mongolar/wrapper"
+	"gopkg.in/mgo.v2/bson"
+	"time"
+)
+
+// The copies made by a clone.  Every element is loaded and copied in memory
+// before anything is inserted, so a missing child leaves nothing behind.
+type clones struct {
+	ids     map[string]string
+	docs    []bson.M
+	content []string
+}
+
+// Copy an element with a new id and return the new id.
+// A deep clone also copies the children of wrappers and slugs, a shallow clone
+// reuses them.  Children used more than once in the tree are copied once.
+func Clone(id string, deep bool, w *wrapper.Wrapper) (string, error) {
+	l, err := CloneList([]string{id}, deep, w)
+	if err != nil {
+		return "", err
+	}
+	return l[0], nil
+}
+
+// Clone a list of elements, used for the elements of wrappers and paths.
+func CloneList(ids []string, deep bool, w *wrapper.Wrapper) ([]string, error) {
+	cl := &clones{ids: make(map[string]string)}
+	l := make([]string, 0)
+	for _, id := range ids {
+		nid, err := cl.clone(id, deep, w)
+		if err != nil {
+			return nil, err
+		}
+		l = append(l, nid)
+	}
+	err := cl.insert(w)
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (cl *clones) clone(id string, deep bool, w *wrapper.Wrapper) (string, error) {
+	if nid, ok := cl.ids[id]; ok {
+		return nid, nil
+	}
+	if !bson.IsObjectIdHex(id) {
+		return "", errors.New("Invalid Id Hex")
+	}
+	var d bson.M
+	c := w.DbSession.DB("").C("elements")
+	err := c.FindId(bson.ObjectIdHex(id)).One(&d)
+	if err != nil {
+		return "", fmt.Errorf("Unable to clone %s: %s", id, err.Error())
+	}
+	nid := bson.NewObjectId()
+	cl.ids[id] = nid.Hex()
+	d["_id"] = nid
+	if deep {
+		err = cl.children(d, w)
+		if err != nil {
+			return "", err
+		}
+	}
+	d["updated"] = time.Now()
+	cl.docs = append(cl.docs, d)
+	if d["controller"] == "content" {
+		cl.content = append(cl.content, id)
+	}
+	return nid.Hex(), nil
+}
+
+// Replace the children of wrappers and slugs with deep clones.
+func (cl *clones) children(d bson.M, w *wrapper.Wrapper) error {
+	cv, ok := d["controller_values"].(bson.M)
+	if !ok {
+		return nil
+	}
+	switch d["controller"] {
+	case "wrapper":
+		items, _ := cv["elements"].([]interface{})
+		l := make([]string, 0)
+		for _, item := range items {
+			eid, _ := item.(string)
+			nid, err := cl.clone(eid, true, w)
+			if err != nil {
+				return err
+			}
+			l = append(l, nid)
+		}
+		cv["elements"] = l
+	case "slug":
+		for slug, item := range cv {
+			eid, _ := item.(string)
+			nid, err := cl.clone(eid, true, w)
+			if err != nil {
+				return err
+			}
+			cv[slug] = nid
+		}
+	}
+	return nil
+}
+
+// Insert the copies and their references, removing what was inserted when
+// one of them fails.
+func (cl *clones) insert(w *wrapper.Wrapper) error {
+	c := w.DbSession.DB("").C("elements")
+	inserted := make([]bson.ObjectId, 0)
+	var err error
+	for _, d := range cl.docs {
+		err = c.Insert(d)
+		if err != nil {
+			break
+		}
+		inserted = append(inserted, d["_id"].(bson.ObjectId))
+	}
+	if err == nil {
+		for _, id := range cl.content {
+			err = references.Copy(id, cl.ids[id], w)
+			if err != nil {
+				break
+			}
+		}
+	}
+	if err == nil {
+		return nil
+	}
+	rerr := cl.remove(inserted, w)
+	if rerr != nil {
+		return fmt.Errorf("%s, and the copies could not be removed: %s", err.Error(), rerr.Error())
+	}
+	return err
+}
+
+func (cl *clones) remove(inserted []bson.ObjectId, w *wrapper.Wrapper) error {
+	for _, id := range cl.content {
+		err := references.DeleteSource(cl.ids[id], w)
+		if err != nil {
+			return err
+		}
+	}
+	_, err := w.DbSession.DB("").C("elements").RemoveAll(bson.M{"_id": bson.M{"$in": inserted}})
+	return err
+}
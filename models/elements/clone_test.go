@@ -0,0 +1,41 @@
+package elements
+
+import (
+	"gopkg.in/mgo.v2/bson"
+	"reflect"
+	"testing"
+)
+
+// Children that were already copied are reused, so no database is needed.
+func TestCloneChildren(t *testing.T) {
+	cl := &clones{ids: map[string]string{"a": "a2", "b": "b2"}}
+	tests := []struct {
+		doc      bson.M
+		expected interface{}
+	}{
+		{
+			bson.M{"controller": "wrapper", "controller_values": bson.M{"elements": []interface{}{"a", "b", "a"}}},
+			bson.M{"elements": []string{"a2", "b2", "a2"}},
+		},
+		{
+			bson.M{"controller": "slug", "controller_values": bson.M{"home": "a", "about": "b"}},
+			bson.M{"home": "a2", "about": "b2"},
+		},
+		{
+			bson.M{"controller": "content", "controller_values": bson.M{"type": "page"}},
+			bson.M{"type": "page"},
+		},
+	}
+	for _, test := range tests {
+		err := cl.children(test.doc, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(test.doc["controller_values"], test.expected) {
+			t.Errorf("%s values are %v, expected %v", test.doc["controller"], test.doc["controller_values"], test.expected)
+		}
+	}
+	if len(cl.docs) != 0 {
+		t.Errorf("reused children were copied again")
+	}
+}
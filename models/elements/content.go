@@ -31,3 +31,25 @@ func LoadContentElement(i string, w *wrapper.Wrapper) (ContentElement, error) {
 	err := GetValidElement(i, "content", &e, w)
 	return e, err
 }
+
+// Load content elements by id with one query for the elements that are not
+// cached.  Ids that are not found or are not content elements are left out.
+func LoadContentElements(ids []string, w *wrapper.Wrapper) (map[string]ContentElement, error) {
+	loaded := make(map[string]ContentElement)
+	found, err := loadRaw(ids, w)
+	if err != nil {
+		return loaded, err
+	}
+	for id, raw := range found {
+		e := NewContentElement()
+		err = raw.Unmarshal(&e)
+		if err != nil {
+			return loaded, err
+		}
+		if e.Controller != "content" {
+			continue
+		}
+		loaded[id] = e
+	}
+	return loaded, nil
+}
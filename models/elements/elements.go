@@ -2,8 +2,12 @@ package elements
 
 import (
 	"errors"
+	"github.com/mongolar/mongolar/configs"
+	"github.com/mongolar/mongolar/listing"
 	"github.com/mongolar/mongolar/wrapper"
+	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
+	"time"
 )
 
 //The designated structure for all elements
@@ -14,6 +18,11 @@ type Element struct {
 	DynamicId  string        `bson:"dynamic_id,omitempty" json:"mongolardyn,omitempty"`
 	Title      string        `bson:"title" json:"title"`
 	Classes    string        `bson:"classes" json:"mongolarclasses,omitempty"`
+	// Set on every save, used for Last-Modified
+	Updated time.Time `bson:"updated,omitempty" json:"-"`
+	// Seconds public responses for the element can be cached, 0 uses the
+	// site's CacheMaxAge for the controller and a negative value disables caching.
+	CacheMaxAge int `bson:"cache_max_age,omitempty" json:"-"`
 }
 
 func (e *Element) Save(w *wrapper.Wrapper) error {
@@ -36,14 +45,61 @@ func LoadElement(i string, w *wrapper.Wrapper) (Element, error) {
 
 //Save an element in its current state.
 func Save(id bson.ObjectId, v interface{}, w *wrapper.Wrapper) error {
+	b, err := bson.Marshal(v)
+	if err != nil {
+		return err
+	}
+	d := bson.M{}
+	err = bson.Unmarshal(b, &d)
+	if err != nil {
+		return err
+	}
+	d["updated"] = time.Now()
 	c := w.DbSession.DB("").C("elements")
-	_, err := c.Upsert(bson.M{"_id": id}, v)
+	_, err = c.Upsert(bson.M{"_id": id}, d)
+	Invalidate(w, id.Hex())
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// Add the updated time to an update of elements written without Save.
+func Stamp(update bson.M) bson.M {
+	set, ok := update["$set"].(bson.M)
+	if !ok {
+		set = bson.M{}
+		update["$set"] = set
+	}
+	set["updated"] = time.Now()
+	return update
+}
+
+// The seconds a public response for an element can be cached.
+func MaxAge(e Element, s *configs.SiteConfig) int {
+	if e.CacheMaxAge > 0 {
+		return e.CacheMaxAge
+	}
+	if e.CacheMaxAge < 0 {
+		return 0
+	}
+	return s.CacheMaxAge[e.Controller]
+}
+
+// The latest updated time of elements, zero if any of them was never stamped.
+func LastModified(el ...Element) time.Time {
+	var t time.Time
+	for _, e := range el {
+		if e.Updated.IsZero() {
+			return time.Time{}
+		}
+		if e.Updated.After(t) {
+			t = e.Updated
+		}
+	}
+	return t
+}
+
 // Query one element
 func GetElement(b bson.M, v interface{}, w *wrapper.Wrapper) error {
 	c := w.DbSession.DB("").C("elements")
@@ -51,24 +107,61 @@ func GetElement(b bson.M, v interface{}, w *wrapper.Wrapper) error {
 	return err
 }
 
-// Get one element given an id
+// Get one element given an id, elements are read through the site's cache.
 func GetById(i string, v interface{}, w *wrapper.Wrapper) error {
-	if !bson.IsObjectIdHex(i) {
-		return errors.New("Invalid Id Hex")
-	}
-	b := bson.M{"_id": bson.ObjectIdHex(i)}
-	err := GetElement(b, v, w)
-	return err
+	return GetValidElement(i, "", v, w)
 }
 
 // Get one element by id and controller path, most common query because you should validate your controller against the id
+// An empty controller matches any element.
 func GetValidElement(i string, c string, v interface{}, w *wrapper.Wrapper) error {
 	if !bson.IsObjectIdHex(i) {
 		return errors.New("Invalid Id Hex")
 	}
-	b := bson.M{"_id": bson.ObjectIdHex(i), "controller": c}
-	err := GetElement(b, v, w)
-	return err
+	found, err := loadRaw([]string{i}, w)
+	if err != nil {
+		return err
+	}
+	raw, ok := found[i]
+	if !ok {
+		return mgo.ErrNotFound
+	}
+	if c != "" {
+		var e Element
+		err = raw.Unmarshal(&e)
+		if err != nil {
+			return err
+		}
+		if e.Controller != c {
+			return mgo.ErrNotFound
+		}
+	}
+	return raw.Unmarshal(v)
+}
+
+// Get elements by id in the order given with one query for the elements that
+// are not cached.  The ids that were not found are returned.
+func GetElements(ids []string, w *wrapper.Wrapper) ([]Element, []string, error) {
+	el := make([]Element, 0)
+	missing := make([]string, 0)
+	found, err := loadRaw(ids, w)
+	if err != nil {
+		return el, missing, err
+	}
+	for _, id := range ids {
+		raw, ok := found[id]
+		if !ok {
+			missing = append(missing, id)
+			continue
+		}
+		e := NewElement()
+		err = raw.Unmarshal(&e)
+		if err != nil {
+			return el, missing, err
+		}
+		el = append(el, e)
+	}
+	return el, missing, nil
 }
 
 func Delete(id string, w *wrapper.Wrapper) error {
@@ -77,17 +170,40 @@ func Delete(id string, w *wrapper.Wrapper) error {
 	}
 	c := w.DbSession.DB("").C("elements")
 	i := bson.M{"_id": bson.ObjectIdHex(id)}
-	return c.Remove(i)
+	err := c.Remove(i)
+	Invalidate(w, id)
+	return err
 }
 
 // Get all Elements
 func ElementList(w *wrapper.Wrapper) ([]Element, error) {
 	el := make([]Element, 0)
 	c := w.DbSession.DB("").C("elements")
-	i := c.Find(nil).Limit(50).Iter()
+	i := c.Find(nil).Iter()
 	err := i.All(&el)
 	if err != nil {
 		return nil, err
 	}
 	return el, nil
 }
+
+// Sorts and filters available when listing elements
+var Listing = listing.Definition{
+	Sorts: map[string]string{
+		"title":      "title",
+		"controller": "controller",
+	},
+	Filters: map[string]listing.Filter{
+		"title":        listing.Filter{Field: "title", Partial: true},
+		"controller":   listing.Filter{Field: "controller"},
+		"content_type": listing.Filter{Field: "controller_values.type"},
+	},
+}
+
+// Get one page of Elements
+func ElementPage(q listing.Query, w *wrapper.Wrapper) ([]Element, listing.Page, error) {
+	el := make([]Element, 0)
+	c := w.DbSession.DB("").C("elements")
+	p, err := q.Run(c, &el)
+	return el, p, err
+}
@@ -0,0 +1,33 @@
+package elements
+
+import (
+	"github.com/mongolar/mongolar/wrapper"
+)
+
+type ImageValues struct {
+	Media   string `bson:"media" json:"media"`
+	Size    string `bson:"size" json:"size"`
+	Alt     string `bson:"alt" json:"alt"`
+	Caption string `bson:"caption" json:"caption"`
+}
+
+type ImageElement struct {
+	ImageValues `bson:"controller_values" json:"content"`
+	Element     `bson:",inline"`
+}
+
+func (ie *ImageElement) Save(w *wrapper.Wrapper) error {
+	return Save(ie.Element.MongoId, ie, w)
+}
+
+func NewImageElement() ImageElement {
+	e := NewElement()
+	ie := ImageElement{Element: e}
+	return ie
+}
+
+func LoadImageElement(i string, w *wrapper.Wrapper) (ImageElement, error) {
+	e := NewImageElement()
+	err := GetValidElement(i, "image", &e, w)
+	return e, err
+}
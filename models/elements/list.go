@@ -0,0 +1,144 @@
+package elements
+
+import (
+	"errors"
+	"github.com/mongolar/mongolar/form"
+	"github.com/mongolar/mongolar/wrapper"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"strings"
+)
+
+// Page size used when a list does not set one, and the largest allowed
+const (
+	DefaultPageSize = 10
+	MaxPageSize     = 100
+)
+
+// The query of a list element.
+// Filters match content fields exactly, values are coerced to the type of the
+// field in the content type so "true" matches a checkbox and "5" a number.  Sort is a content field prefixed with
+// "-" to sort descending.  ItemTemplate is the template each item is rendered with.
+type ListValues struct {
+	ContentType  string            `bson:"content_type" json:"content_type"`
+	Filters      map[string]string `bson:"filters" json:"filters"`
+	Sort         string            `bson:"sort" json:"sort"`
+	PageSize     int               `bson:"page_size" json:"page_size"`
+	ItemTemplate string            `bson:"item_template" json:"item_template"`
+}
+
+type ListElement struct {
+	ListValues `bson:"controller_values" json:"content"`
+	Element    `bson:",inline"`
+}
+
+// Page metadata for list results, pages are numbered from 1
+type ListPage struct {
+	Number int `json:"number"`
+	Size   int `json:"size"`
+	Total  int `json:"total"`
+	Pages  int `json:"pages"`
+}
+
+func (le *ListElement) Save(w *wrapper.Wrapper) error {
+	return Save(le.Element.MongoId, le, w)
+}
+
+func NewListElement() ListElement {
+	e := NewElement()
+	lv := ListValues{Filters: make(map[string]string)}
+	le := ListElement{Element: e, ListValues: lv}
+	return le
+}
+
+func LoadListElement(i string, w *wrapper.Wrapper) (ListElement, error) {
+	e := NewListElement()
+	err := GetValidElement(i, "list", &e, w)
+	return e, err
+}
+
+// Get one page of the content elements matching the list query.
+func (le *ListElement) Query(page int, w *wrapper.Wrapper) ([]ContentElement, ListPage, error) {
+	p := ListPage{Number: page, Size: le.PageSize}
+	if p.Number < 1 {
+		p.Number = 1
+	}
+	if p.Size <= 0 {
+		p.Size = DefaultPageSize
+	}
+	if p.Size > MaxPageSize {
+		p.Size = MaxPageSize
+	}
+	s := bson.M{"controller": "content", "controller_values.type": le.ContentType}
+	filters, err := le.filterValues(w)
+	if err != nil {
+		return nil, p, err
+	}
+	for field, value := range filters {
+		s["controller_values.content."+field] = value
+	}
+	sort := []string{"_id"}
+	if le.Sort != "" {
+		field := strings.TrimPrefix(le.Sort, "-")
+		if !validField(field) {
+			return nil, p, errors.New("Invalid sort field " + field)
+		}
+		direction := ""
+		if strings.HasPrefix(le.Sort, "-") {
+			direction = "-"
+		}
+		sort = []string{direction + "controller_values.content." + field, direction + "_id"}
+	}
+	c := w.DbSession.DB("").C("elements")
+	total, err := c.Find(s).Count()
+	if err != nil {
+		return nil, p, err
+	}
+	p.Total = total
+	p.Pages = (total + p.Size - 1) / p.Size
+	ces := make([]ContentElement, 0)
+	err = c.Find(s).Sort(sort...).Skip((p.Number - 1) * p.Size).Limit(p.Size).All(&ces)
+	return ces, p, err
+}
+
+// Coerce the filters with the fields of the content type the way content is
+// coerced when it is saved.  Reference fields are matched by a single id and
+// fields the content type does not have are matched as strings.
+func (le *ListElement) filterValues(w *wrapper.Wrapper) (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	if len(le.Filters) == 0 {
+		return values, nil
+	}
+	var ct struct {
+		Form []*form.Field `bson:"form"`
+	}
+	err := w.DbSession.DB("").C("content_types").Find(bson.M{"type": le.ContentType}).One(&ct)
+	if err != nil && err != mgo.ErrNotFound {
+		return nil, err
+	}
+	fields := make(map[string]*form.Field)
+	for _, f := range ct.Form {
+		fields[f.Key] = f
+	}
+	for field, value := range le.Filters {
+		if !validField(field) {
+			return nil, errors.New("Invalid filter field " + field)
+		}
+		f, ok := fields[field]
+		if !ok || f.Type == "reference" {
+			values[field] = value
+			continue
+		}
+		v, message := f.Coerce(value)
+		if message != "" {
+			return nil, errors.New("Invalid filter value for " + field + ": " + message)
+		}
+		values[field] = v
+	}
+	return values, nil
+}
+
+// Content field names can not be operators.
+func validField(f string) bool {
+	return f != "" && !strings.ContainsAny(f, "$")
+}
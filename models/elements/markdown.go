@@ -0,0 +1,41 @@
+package elements
+
+import (
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/mongolar/mongolar/wrapper"
+	"github.com/russross/blackfriday"
+)
+
+// The markdown source is kept for the editor, Html is rendered from it on save.
+type MarkdownValues struct {
+	Source string `bson:"source" json:"source"`
+	Html   string `bson:"html" json:"html"`
+}
+
+type MarkdownElement struct {
+	MarkdownValues `bson:"controller_values" json:"content"`
+	Element        `bson:",inline"`
+}
+
+func (me *MarkdownElement) Save(w *wrapper.Wrapper) error {
+	me.Html = RenderMarkdown(me.Source)
+	return Save(me.Element.MongoId, me, w)
+}
+
+func NewMarkdownElement() MarkdownElement {
+	e := NewElement()
+	me := MarkdownElement{Element: e}
+	return me
+}
+
+func LoadMarkdownElement(i string, w *wrapper.Wrapper) (MarkdownElement, error) {
+	e := NewMarkdownElement()
+	err := GetValidElement(i, "markdown", &e, w)
+	return e, err
+}
+
+// Render markdown to html, the html is sanitized so editors can not add scripts.
+func RenderMarkdown(source string) string {
+	unsafe := blackfriday.MarkdownCommon([]byte(source))
+	return string(bluemonday.UGCPolicy().SanitizeBytes(unsafe))
+}
@@ -6,8 +6,12 @@ import (
 	"gopkg.in/mgo.v2/bson"
 )
 
+// Slug elements load one of their children by a value from the url.
+// Param names the path parameter holding the value, without it the rest of a
+// wildcard path is used.
 type SlugElement struct {
 	Slugs   map[string]string `bson:"controller_values"`
+	Param   string            `bson:"slug_param,omitempty" json:"slug_param,omitempty"`
 	Element `bson:"_,inline"`
 }
 
@@ -34,7 +38,7 @@ func SlugDeleteAllChild(id string, w *wrapper.Wrapper) error {
 	}
 	slugelements := make([]SlugElement, 0)
 	c := w.DbSession.DB("").C("elements")
-	i := c.Find(bson.M{"controller": "slug"}).Limit(50).Iter()
+	i := c.Find(bson.M{"controller": "slug"}).Iter()
 	err := i.All(&slugelements)
 	if err != nil {
 		if err.Error() == "not found" {
@@ -44,7 +44,15 @@ func WrapperDeleteAllChild(id string, w *wrapper.Wrapper) error {
 	}
 	c := w.DbSession.DB("").C("elements")
 	s := bson.M{"controller_values.elements": id}
-	d := bson.M{"$pull": bson.M{"controller_values.elements": id}}
-	_, err := c.UpdateAll(s, d)
+	d := Stamp(bson.M{"$pull": bson.M{"controller_values.elements": id}})
+	el := make([]Element, 0)
+	err := c.Find(s).Select(bson.M{"_id": 1}).All(&el)
+	if err != nil {
+		return err
+	}
+	_, err = c.UpdateAll(s, d)
+	for _, e := range el {
+		Invalidate(w, e.MongoId.Hex())
+	}
 	return err
 }
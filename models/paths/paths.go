@@ -2,9 +2,11 @@ package paths
 
 import (
 	"errors"
+	"github.com/mongolar/mongolar/listing"
+	"github.com/mongolar/mongolar/models/elements"
 	"github.com/mongolar/mongolar/wrapper"
+	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
-	"path"
 	"strings"
 )
 
@@ -16,7 +18,11 @@ type Path struct {
 	Template     string        `bson:"template" json:"template"`
 	Status       string        `bson:"status" json:"status"`
 	Title        string        `bson:"title" json:"title"`
+	Pattern      bool          `bson:"pattern,omitempty" json:"-"`
+	Segments     int           `bson:"segments,omitempty" json:"-"`
 	PathElements `bson:",inline,omitempty"`
+	// Parameters matched by PathMatch
+	Params map[string]string `bson:"-" json:"-"`
 }
 
 // PathElements Structure to define the Elements in a path for easy json and bson marshalling.
@@ -24,6 +30,30 @@ type PathElements struct {
 	Elements []string `bson:"elements,omitempty" json:"elements,omitempty"`
 }
 
+// Functions called with paths that were changed, renamed paths are passed
+// with their old and new urls.
+type ChangeHook func(p Path, w *wrapper.Wrapper)
+
+var changeHooks = make([]ChangeHook, 0)
+
+// Add a function to call when paths are saved or deleted.
+func OnChange(h ChangeHook) {
+	changeHooks = append(changeHooks, h)
+}
+
+// Run the change hooks for a path written without Save, like a restored path.
+func Changed(p Path, w *wrapper.Wrapper) {
+	changed([]Path{p}, w)
+}
+
+func changed(pl []Path, w *wrapper.Wrapper) {
+	for _, p := range pl {
+		for _, h := range changeHooks {
+			h(p, w)
+		}
+	}
+}
+
 // Constructor for paths
 func NewPath() Path {
 	e := NewPathElements()
@@ -61,11 +91,25 @@ func (p *Path) Save(w *wrapper.Wrapper) error {
 	if p.Status == "" {
 		return errors.New("Status required")
 	}
+	_, err := parse(p.Path)
+	if err != nil {
+		return err
+	}
+	p.Pattern = IsPattern(p.Path)
+	p.Segments = SegmentCount(p.Path)
 	c := w.DbSession.DB("").C("paths")
-	_, err := c.Upsert(bson.M{"_id": p.MongoId}, p)
+	var old Path
+	oerr := c.FindId(p.MongoId).One(&old)
+	_, err = c.Upsert(bson.M{"_id": p.MongoId}, p)
+	Invalidate(w.SiteConfig)
 	if err != nil {
 		return err
 	}
+	pl := []Path{*p}
+	if oerr == nil && (old.Path != p.Path || old.Wildcard != p.Wildcard) {
+		pl = append(pl, old)
+	}
+	changed(pl, w)
 	return nil
 }
 
@@ -80,31 +124,124 @@ func (p *Path) GetById(i string, w *wrapper.Wrapper) error {
 }
 
 // Given a URL and status this query will attempt to find a matching path.
-// First the query qill attempt to implicitly match the url without a wildcard
-// Then it will attempt to match based on the same path value having a wildcard
-// After that it will remove sections of the url each time looking for a wildcard match
+// First the query will attempt to implicitly match the url without a wildcard
+// Then it will attempt to match a path with named parameters, like "/blog/:year/:slug"
+// After that it will remove sections of the url each time looking for a wildcard match,
+// the longest match wins and static paths win over patterns of the same length.
+// The matched parameters are set on the path, with the rest of a wildcard match
+// under Rest, and the rest is also returned.
 // If it does not find any  matches it retrns the last error.
+// Published paths are resolved from the site's route table, other statuses
+// are queried.
 func (p *Path) PathMatch(u string, s string, w *wrapper.Wrapper) (string, error) {
+	if s == "published" {
+		return p.routeMatch(u, w)
+	}
 	c := w.DbSession.DB("").C("paths")
-	var rejects []string
-	wildcard := false
-	var err error
-	for {
-		b := bson.M{"path": u, "wildcard": wildcard, "status": s}
-		err = c.Find(b).One(p)
-		wildcard = true
-		// If query doesnt return anything
-		if err != nil {
-			rejects = append([]string{path.Base(u)}, rejects...)
-			u = path.Dir(u)
-			if u == "/" {
+	parts := split(u)
+	b := bson.M{"path": u, "wildcard": false, "pattern": bson.M{"$ne": true}, "status": s}
+	err := c.Find(b).One(p)
+	if err == nil {
+		p.Params = make(map[string]string)
+		return "", nil
+	}
+	if err != mgo.ErrNotFound {
+		return "", err
+	}
+	best, err := matchPattern(parts, s, w)
+	if err != nil {
+		return "", err
+	}
+	if best == nil || best.path.Wildcard {
+		// Static wildcards only need to be tried where they would beat the pattern.
+		min := 1
+		if best != nil {
+			min = len(best.segments)
+		}
+		for i := len(parts); i >= min; i-- {
+			prefix := "/" + strings.Join(parts[:i], "/")
+			if prefix == "/" {
+				break
+			}
+			var sp Path
+			b = bson.M{"path": prefix, "wildcard": true, "pattern": bson.M{"$ne": true}, "status": s}
+			err = c.Find(b).One(&sp)
+			if err == nil {
+				best = &candidate{path: sp, params: make(map[string]string), rest: parts[i:]}
 				break
 			}
+			if err != mgo.ErrNotFound {
+				return "", err
+			}
+		}
+	}
+	if best == nil {
+		return "", mgo.ErrNotFound
+	}
+	return p.matched(best), nil
+}
+
+// Set the path to a match and return the rest of the url.
+// Elements are copied so the route table is never changed through the path.
+func (p *Path) matched(c *candidate) string {
+	*p = c.path
+	p.Elements = append(make([]string, 0), c.path.Elements...)
+	p.Params = c.params
+	rest := strings.Join(c.rest, "/")
+	if rest != "" {
+		p.Params[Rest] = rest
+	}
+	return rest
+}
+
+// A pattern path matching a url
+type candidate struct {
+	path     Path
+	segments []segment
+	params   map[string]string
+	rest     []string
+}
+
+// Patterns matching the whole url beat wildcards, then longer and more
+// static patterns win.
+func (c *candidate) better(o *candidate) bool {
+	if o == nil {
+		return true
+	}
+	if c.path.Wildcard != o.path.Wildcard {
+		return !c.path.Wildcard
+	}
+	if len(c.segments) != len(o.segments) {
+		return len(c.segments) > len(o.segments)
+	}
+	return static(c.segments) > static(o.segments)
+}
+
+// Find the best pattern path matching the segments of a url.
+func matchPattern(parts []string, s string, w *wrapper.Wrapper) (*candidate, error) {
+	pl := make([]Path, 0)
+	c := w.DbSession.DB("").C("paths")
+	b := bson.M{"pattern": true, "status": s, "segments": bson.M{"$lte": len(parts)}}
+	err := c.Find(b).Sort("path").All(&pl)
+	if err != nil {
+		return nil, err
+	}
+	var best *candidate
+	for _, pp := range pl {
+		segments, err := parse(pp.Path)
+		if err != nil {
 			continue
 		}
-		break
+		params, rest, ok := match(segments, parts, pp.Wildcard)
+		if !ok {
+			continue
+		}
+		mc := &candidate{path: pp, segments: segments, params: params, rest: rest}
+		if mc.better(best) {
+			best = mc
+		}
 	}
-	return strings.Join(rejects, "/"), err
+	return best, nil
 }
 
 // Add a child element to an existing Path
@@ -120,6 +257,36 @@ func AddChild(pathid string, elementid string, w *wrapper.Wrapper) error {
 	return p.Save(w)
 }
 
+// Copy a path to a new url.  A deep copy clones its whole element tree,
+// a shallow copy reuses the elements.
+// The copy is unpublished so it can be edited before it goes live.
+func Duplicate(id string, u string, deep bool, w *wrapper.Wrapper) (Path, error) {
+	p, err := LoadPath(id, w)
+	if err != nil {
+		return p, err
+	}
+	if u == p.Path {
+		return p, errors.New("Duplicate path requires a new url")
+	}
+	// The url is checked before the elements are cloned so an invalid url
+	// leaves no copies behind.
+	_, err = parse(u)
+	if u == "" || err != nil {
+		return p, errors.New("Duplicate path requires a valid url")
+	}
+	if deep {
+		p.Elements, err = elements.CloneList(p.Elements, true, w)
+		if err != nil {
+			return p, err
+		}
+	}
+	p.MongoId = bson.NewObjectId()
+	p.Path = u
+	p.Status = "unpublished"
+	err = p.Save(w)
+	return p, err
+}
+
 // Delete a path by id.
 func Delete(id string, w *wrapper.Wrapper) error {
 	if !bson.IsObjectIdHex(id) {
@@ -127,7 +294,14 @@ func Delete(id string, w *wrapper.Wrapper) error {
 	}
 	c := w.DbSession.DB("").C("paths")
 	i := bson.M{"_id": bson.ObjectIdHex(id)}
-	return c.Remove(i)
+	var old Path
+	oerr := c.Find(i).One(&old)
+	err := c.Remove(i)
+	Invalidate(w.SiteConfig)
+	if err == nil && oerr == nil {
+		changed([]Path{old}, w)
+	}
+	return err
 }
 
 // Delete all references to a child element in all paths by id.
@@ -138,7 +312,16 @@ func DeleteAllChild(id string, w *wrapper.Wrapper) error {
 	s := bson.M{"elements": id}
 	d := bson.M{"$pull": bson.M{"elements": id}}
 	c := w.DbSession.DB("").C("paths")
-	_, err := c.UpdateAll(s, d)
+	pl := make([]Path, 0)
+	err := c.Find(s).All(&pl)
+	if err != nil {
+		return err
+	}
+	_, err = c.UpdateAll(s, d)
+	Invalidate(w.SiteConfig)
+	if err == nil {
+		changed(pl, w)
+	}
 	return err
 
 }
@@ -147,10 +330,32 @@ func DeleteAllChild(id string, w *wrapper.Wrapper) error {
 func PathList(w *wrapper.Wrapper) ([]Path, error) {
 	pl := make([]Path, 0)
 	c := w.DbSession.DB("").C("paths")
-	i := c.Find(nil).Limit(50).Iter()
+	i := c.Find(nil).Iter()
 	err := i.All(&pl)
 	if err != nil {
 		return nil, err
 	}
 	return pl, nil
 }
+
+// Sorts and filters available when listing paths
+var Listing = listing.Definition{
+	Sorts: map[string]string{
+		"path":   "path",
+		"title":  "title",
+		"status": "status",
+	},
+	Filters: map[string]listing.Filter{
+		"path":   listing.Filter{Field: "path", Partial: true},
+		"title":  listing.Filter{Field: "title", Partial: true},
+		"status": listing.Filter{Field: "status"},
+	},
+}
+
+// Get one page of Paths
+func PathPage(q listing.Query, w *wrapper.Wrapper) ([]Path, listing.Page, error) {
+	pl := make([]Path, 0)
+	c := w.DbSession.DB("").C("paths")
+	p, err := q.Run(c, &pl)
+	return pl, p, err
+}
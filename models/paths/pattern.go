@@ -0,0 +1,129 @@
+package paths
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// The parameter holding the rest of the url matched by a wildcard path.
+const Rest = "*"
+
+var paramName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// One segment of a path, either static text or a named parameter with an
+// optional regular expression it must match.
+type segment struct {
+	static string
+	name   string
+	re     *regexp.Regexp
+}
+
+// Check if a path has named parameters, like "/blog/:year/:slug".
+func IsPattern(u string) bool {
+	for _, s := range split(u) {
+		if strings.HasPrefix(s, ":") {
+			return true
+		}
+	}
+	return false
+}
+
+// The static segments before the first parameter of a path.
+func StaticPrefix(u string) string {
+	prefix := make([]string, 0)
+	for _, s := range split(u) {
+		if strings.HasPrefix(s, ":") {
+			break
+		}
+		prefix = append(prefix, s)
+	}
+	return "/" + strings.Join(prefix, "/")
+}
+
+// The number of segments in a path, stored to narrow pattern queries.
+func SegmentCount(u string) int {
+	return len(split(u))
+}
+
+// Parse a path into segments.  Parameters are written ":name" or
+// ":name(regexp)", the expression must match the whole segment and can not
+// contain a "/".
+func parse(u string) ([]segment, error) {
+	parts := split(u)
+	segments := make([]segment, 0)
+	names := make(map[string]bool)
+	for _, p := range parts {
+		if !strings.HasPrefix(p, ":") {
+			segments = append(segments, segment{static: p})
+			continue
+		}
+		name := p[1:]
+		var re *regexp.Regexp
+		if i := strings.Index(name, "("); i >= 0 {
+			if !strings.HasSuffix(name, ")") {
+				return nil, fmt.Errorf("Unclosed expression in %s", p)
+			}
+			var err error
+			re, err = regexp.Compile("^(?:" + name[i+1:len(name)-1] + ")$")
+			if err != nil {
+				return nil, err
+			}
+			name = name[:i]
+		}
+		if !paramName.MatchString(name) {
+			return nil, fmt.Errorf("Invalid parameter name in %s", p)
+		}
+		if names[name] {
+			return nil, errors.New("Duplicate parameter " + name)
+		}
+		names[name] = true
+		segments = append(segments, segment{name: name, re: re})
+	}
+	return segments, nil
+}
+
+// Match the leading segments of a url against a pattern, returning the
+// parameters, the unmatched rest of the url and whether it matched.
+// Only wildcard patterns can leave a rest.
+func match(segments []segment, parts []string, wildcard bool) (map[string]string, []string, bool) {
+	if len(parts) < len(segments) || (!wildcard && len(parts) != len(segments)) {
+		return nil, nil, false
+	}
+	params := make(map[string]string)
+	for i, s := range segments {
+		if s.name == "" {
+			if parts[i] != s.static {
+				return nil, nil, false
+			}
+			continue
+		}
+		if s.re != nil && !s.re.MatchString(parts[i]) {
+			return nil, nil, false
+		}
+		params[s.name] = parts[i]
+	}
+	return params, parts[len(segments):], true
+}
+
+// The number of static segments, used to prefer the most specific pattern.
+func static(segments []segment) int {
+	n := 0
+	for _, s := range segments {
+		if s.name == "" {
+			n++
+		}
+	}
+	return n
+}
+
+func split(u string) []string {
+	parts := make([]string, 0)
+	for _, p := range strings.Split(u, "/") {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return parts
+}
@@ -0,0 +1,117 @@
+package paths
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		path  string
+		valid bool
+	}{
+		{"/blog/:year/:slug", true},
+		{`/blog/:year([0-9]{4})/:slug`, true},
+		{"/blog/:year(/:slug", false},
+		{"/blog/:year([)", false},
+		{"/blog/:1year", false},
+		{"/blog/:slug/:slug", false},
+	}
+	for _, test := range tests {
+		_, err := parse(test.path)
+		if (err == nil) != test.valid {
+			t.Errorf("%s: parse error %v, expected valid %t", test.path, err, test.valid)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		path     string
+		url      string
+		wildcard bool
+		ok       bool
+		params   map[string]string
+		rest     []string
+	}{
+		{"/blog/:slug", "/blog/hello", false, true, map[string]string{"slug": "hello"}, []string{}},
+		{"/blog/:slug", "/blog/hello/more", false, false, nil, nil},
+		{"/blog/:slug", "/blog/hello/more", true, true, map[string]string{"slug": "hello"}, []string{"more"}},
+		{"/blog/:slug", "/news/hello", false, false, nil, nil},
+		{"/blog/:slug", "/blog", true, false, nil, nil},
+		{`/blog/:year([0-9]{4})`, "/blog/2016", false, true, map[string]string{"year": "2016"}, []string{}},
+		{`/blog/:year([0-9]{4})`, "/blog/20161", false, false, nil, nil},
+		{`/blog/:year([0-9]{4})`, "/blog/news", false, false, nil, nil},
+	}
+	for _, test := range tests {
+		segments, err := parse(test.path)
+		if err != nil {
+			t.Fatalf("%s: %s", test.path, err.Error())
+		}
+		params, rest, ok := match(segments, split(test.url), test.wildcard)
+		if ok != test.ok {
+			t.Errorf("%s on %s: matched %t, expected %t", test.url, test.path, ok, test.ok)
+			continue
+		}
+		if !reflect.DeepEqual(params, test.params) || !reflect.DeepEqual(rest, test.rest) {
+			t.Errorf("%s on %s: matched %v %v, expected %v %v", test.url, test.path, params, rest, test.params, test.rest)
+		}
+	}
+}
+
+func TestBetter(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		wildcard bool
+		other    string
+		owild    bool
+		better   bool
+	}{
+		{"exact before wildcard", "/blog/:slug", false, "/blog/:slug", true, true},
+		{"wildcard after exact", "/blog", true, "/:section/:slug", false, false},
+		{"longer wildcard first", "/blog/:slug", true, "/blog", true, true},
+		{"static before parameter", "/blog/about", false, "/blog/:slug", false, true},
+		{"same static segments keep the first", "/:section/about", false, "/blog/:slug", false, false},
+		{"equal paths keep the first", "/blog/:slug", false, "/blog/:name", false, false},
+	}
+	for _, test := range tests {
+		c := testCandidate(t, test.path, test.wildcard)
+		o := testCandidate(t, test.other, test.owild)
+		if c.better(o) != test.better {
+			t.Errorf("%s: better is %t, expected %t", test.name, !test.better, test.better)
+		}
+	}
+	if !testCandidate(t, "/blog", false).better(nil) {
+		t.Errorf("any candidate is better than none")
+	}
+}
+
+func testCandidate(t *testing.T, p string, wildcard bool) *candidate {
+	segments, err := parse(p)
+	if err != nil {
+		t.Fatalf("%s: %s", p, err.Error())
+	}
+	return &candidate{path: Path{Path: p, Wildcard: wildcard}, segments: segments}
+}
+
+func TestStaticPrefix(t *testing.T) {
+	tests := []struct {
+		path   string
+		prefix string
+		count  int
+	}{
+		{"/", "/", 0},
+		{"/blog/:year/:slug", "/blog", 3},
+		{"/blog/archive/:year", "/blog/archive", 3},
+		{"/:section", "/", 1},
+	}
+	for _, test := range tests {
+		if p := StaticPrefix(test.path); p != test.prefix {
+			t.Errorf("%s: prefix is %s, expected %s", test.path, p, test.prefix)
+		}
+		if n := SegmentCount(test.path); n != test.count {
+			t.Errorf("%s: %d segments, expected %d", test.path, n, test.count)
+		}
+	}
+}
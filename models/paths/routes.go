@@ -0,0 +1,201 @@
+package paths
+
+import (
+	"fmt"
+	"github.com/mongolar/mongolar/configs"
+	"github.com/mongolar/mongolar/metrics"
+	"github.com/mongolar/mongolar/wrapper"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"sync"
+	"time"
+)
+
+// How long a route table is used before it is rebuilt.  Tables are dropped
+// whenever this server writes a path, the age only bounds how long changes
+// made by other servers take to show.
+var RouteMaxAge = time.Minute
+
+// A node in the route trie, one per path segment.
+type node struct {
+	static  map[string]*node
+	params  []*paramNode
+	entries []route
+}
+
+// A parameter segment and the node below it.
+type paramNode struct {
+	segment segment
+	node    *node
+}
+
+// A published path ending at a node.
+type route struct {
+	path     Path
+	segments []segment
+}
+
+// The published paths of a site.
+type table struct {
+	root  *node
+	built time.Time
+}
+
+// Route tables by site, with a generation so a table built while paths were
+// being written is never kept.
+var routes = struct {
+	sync.Mutex
+	tables      map[*configs.SiteConfig]*table
+	generations map[*configs.SiteConfig]int
+}{
+	tables:      make(map[*configs.SiteConfig]*table),
+	generations: make(map[*configs.SiteConfig]int),
+}
+
+// Drop the route table of a site, it is rebuilt on the next request.
+// Anything writing to the paths collection directly must call this.
+func Invalidate(s *configs.SiteConfig) {
+	routes.Lock()
+	delete(routes.tables, s)
+	routes.generations[s]++
+	routes.Unlock()
+}
+
+// Get the route table of a site, building it with one query if needed.
+func routeTable(w *wrapper.Wrapper) (*table, error) {
+	return siteRoutes(w.SiteConfig, w.DbSession.DB(""))
+}
+
+// Get the route table of a site outside of a controller.
+func siteRoutes(s *configs.SiteConfig, db *mgo.Database) (*table, error) {
+	routes.Lock()
+	t := routes.tables[s]
+	generation := routes.generations[s]
+	routes.Unlock()
+	if t != nil && time.Since(t.built) < RouteMaxAge {
+		return t, nil
+	}
+	pl := make([]Path, 0)
+	start := time.Now()
+	err := db.C("paths").Find(bson.M{"status": "published"}).Sort("path").All(&pl)
+	metrics.DBDuration.Since(start, s.Name, "routes")
+	if err != nil {
+		return nil, err
+	}
+	t = &table{root: newNode(), built: time.Now()}
+	for _, p := range pl {
+		segments, err := parse(p.Path)
+		if err != nil {
+			errmessage := fmt.Sprintf("Path %s skipped in route table: %s", p.Path, err.Error())
+			s.Logger.Error(errmessage)
+			continue
+		}
+		t.add(p, segments)
+	}
+	routes.Lock()
+	if routes.generations[s] == generation {
+		routes.tables[s] = t
+	}
+	routes.Unlock()
+	return t, nil
+}
+
+func newNode() *node {
+	return &node{static: make(map[string]*node)}
+}
+
+func (t *table) add(p Path, segments []segment) {
+	n := t.root
+	for _, s := range segments {
+		if s.name == "" {
+			child, ok := n.static[s.static]
+			if !ok {
+				child = newNode()
+				n.static[s.static] = child
+			}
+			n = child
+			continue
+		}
+		var child *node
+		for _, pn := range n.params {
+			if pn.segment.name == s.name && sameExpression(pn.segment, s) {
+				child = pn.node
+				break
+			}
+		}
+		if child == nil {
+			child = newNode()
+			n.params = append(n.params, &paramNode{segment: s, node: child})
+		}
+		n = child
+	}
+	n.entries = append(n.entries, route{path: p, segments: segments})
+}
+
+func sameExpression(a segment, b segment) bool {
+	if a.re == nil || b.re == nil {
+		return a.re == b.re
+	}
+	return a.re.String() == b.re.String()
+}
+
+// Find the best path for the segments of a url, with the same precedence as
+// the queries.  Wildcards on "/" are never matched.
+func (t *table) match(parts []string) *candidate {
+	var best *candidate
+	var walk func(n *node, i int)
+	walk = func(n *node, i int) {
+		for _, r := range n.entries {
+			if !r.path.Wildcard && i != len(parts) {
+				continue
+			}
+			if r.path.Wildcard && i == 0 {
+				continue
+			}
+			params, rest, ok := match(r.segments, parts, r.path.Wildcard)
+			if !ok {
+				continue
+			}
+			c := &candidate{path: r.path, segments: r.segments, params: params, rest: rest}
+			if c.better(best) {
+				best = c
+			}
+		}
+		if i == len(parts) {
+			return
+		}
+		if child, ok := n.static[parts[i]]; ok {
+			walk(child, i+1)
+		}
+		for _, pn := range n.params {
+			if pn.segment.re == nil || pn.segment.re.MatchString(parts[i]) {
+				walk(pn.node, i+1)
+			}
+		}
+	}
+	walk(t.root, 0)
+	return best
+}
+
+// Check whether a published path serves a url, including wildcard and
+// pattern paths.
+func Published(u string, s *configs.SiteConfig, db *mgo.Database) (bool, error) {
+	t, err := siteRoutes(s, db)
+	if err != nil {
+		return false, err
+	}
+	return t.match(split(u)) != nil, nil
+}
+
+// Resolve a url against the route table of a site.
+func (p *Path) routeMatch(u string, w *wrapper.Wrapper) (string, error) {
+	t, err := routeTable(w)
+	if err != nil {
+		return "", err
+	}
+	best := t.match(split(u))
+	if best == nil {
+		return "", mgo.ErrNotFound
+	}
+	return p.matched(best), nil
+}
@@ -0,0 +1,64 @@
+package paths
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTableMatch(t *testing.T) {
+	tb := &table{root: newNode()}
+	for _, p := range []Path{
+		Path{Path: "/"},
+		Path{Path: "/", Wildcard: true},
+		Path{Path: "/blog"},
+		Path{Path: "/blog", Wildcard: true},
+		Path{Path: "/blog/about"},
+		Path{Path: "/blog/:slug"},
+		Path{Path: `/blog/:year([0-9]{4})/:slug`},
+		Path{Path: "/docs/:section", Wildcard: true},
+		Path{Path: "/:section/index"},
+	} {
+		segments, err := parse(p.Path)
+		if err != nil {
+			t.Fatalf("%s: %s", p.Path, err.Error())
+		}
+		tb.add(p, segments)
+	}
+	tests := []struct {
+		url      string
+		path     string
+		wildcard bool
+		params   map[string]string
+		rest     []string
+	}{
+		{"/", "/", false, map[string]string{}, []string{}},
+		{"/blog", "/blog", false, map[string]string{}, []string{}},
+		{"/blog/about", "/blog/about", false, map[string]string{}, []string{}},
+		{"/blog/hello", "/blog/:slug", false, map[string]string{"slug": "hello"}, []string{}},
+		{"/blog/2016/hello", `/blog/:year([0-9]{4})/:slug`, false, map[string]string{"year": "2016", "slug": "hello"}, []string{}},
+		{"/blog/news/hello", "/blog", true, map[string]string{}, []string{"news", "hello"}},
+		{"/docs/api/v1/index", "/docs/:section", true, map[string]string{"section": "api"}, []string{"v1", "index"}},
+		{"/docs/index", "/:section/index", false, map[string]string{"section": "docs"}, []string{}},
+		{"/news/index", "/:section/index", false, map[string]string{"section": "news"}, []string{}},
+		{"/missing", "", false, nil, nil},
+	}
+	for _, test := range tests {
+		c := tb.match(split(test.url))
+		if test.path == "" {
+			if c != nil {
+				t.Errorf("%s: matched %s, expected nothing", test.url, c.path.Path)
+			}
+			continue
+		}
+		if c == nil {
+			t.Errorf("%s: matched nothing, expected %s", test.url, test.path)
+			continue
+		}
+		if c.path.Path != test.path || c.path.Wildcard != test.wildcard {
+			t.Errorf("%s: matched %s wildcard %t, expected %s wildcard %t", test.url, c.path.Path, c.path.Wildcard, test.path, test.wildcard)
+		}
+		if !reflect.DeepEqual(c.params, test.params) || !reflect.DeepEqual(c.rest, test.rest) {
+			t.Errorf("%s: matched %v %v, expected %v %v", test.url, c.params, c.rest, test.params, test.rest)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+// Redirects send visitors from old urls to new ones.
+// Exact redirects match a single url, prefix redirects match a url and
+// everything below it and keep the rest of the url, so "/blog" to "/news"
+// sends "/blog/2015/hello" to "/news/2015/hello".
+// Redirects never apply to a url a published path serves, including wildcard
+// and pattern paths, so they can not hide content.
+
+package redirects
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/mongolar/mongolar/configs"
+	"github.com/mongolar/mongolar/listing"
+	"github.com/mongolar/mongolar/metrics"
+	"github.com/mongolar/mongolar/models/paths"
+	"github.com/mongolar/mongolar/wrapper"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Redirect Structure
+type Redirect struct {
+	MongoId   bson.ObjectId `bson:"_id" json:"id"`
+	From      string        `bson:"from" json:"from"`
+	To        string        `bson:"to" json:"to"`
+	Prefix    bool          `bson:"prefix" json:"prefix"`
+	Status    int           `bson:"status" json:"status"`
+	Automatic bool          `bson:"automatic" json:"automatic"`
+	Created   time.Time     `bson:"created" json:"created"`
+}
+
+// Sorts and filters available when listing redirects
+var Listing = listing.Definition{
+	Sorts: map[string]string{
+		"from":    "from",
+		"to":      "to",
+		"created": "created",
+	},
+	Filters: map[string]listing.Filter{
+		"from": listing.Filter{Field: "from", Partial: true},
+		"to":   listing.Filter{Field: "to", Partial: true},
+	},
+}
+
+// Constructor for redirects
+func NewRedirect() Redirect {
+	return Redirect{MongoId: bson.NewObjectId(), Status: 301, Created: time.Now()}
+}
+
+// Get a redirect by id
+func LoadRedirect(id string, w *wrapper.Wrapper) (Redirect, error) {
+	var r Redirect
+	if !bson.IsObjectIdHex(id) {
+		return r, errors.New("Invalid Id Hex")
+	}
+	err := w.DbSession.DB("").C("redirects").FindId(bson.ObjectIdHex(id)).One(&r)
+	return r, err
+}
+
+// Save a redirect in its current state.
+func (r *Redirect) Save(w *wrapper.Wrapper) error {
+	err := r.validate()
+	if err != nil {
+		return err
+	}
+	if !r.MongoId.Valid() {
+		r.MongoId = bson.NewObjectId()
+	}
+	if r.Created.IsZero() {
+		r.Created = time.Now()
+	}
+	_, err = w.DbSession.DB("").C("redirects").Upsert(bson.M{"_id": r.MongoId}, r)
+	return err
+}
+
+func (r *Redirect) validate() error {
+	if !strings.HasPrefix(r.From, "/") {
+		return errors.New("From must start with /")
+	}
+	if r.To == "" {
+		return errors.New("To required")
+	}
+	if r.From == r.To {
+		return errors.New("A redirect can not point to itself")
+	}
+	if r.Status == 0 {
+		r.Status = 301
+	}
+	if r.Status != 301 && r.Status != 302 {
+		return errors.New("Status must be 301 or 302")
+	}
+	return nil
+}
+
+// Delete a redirect by id.
+func Delete(id string, w *wrapper.Wrapper) error {
+	if !bson.IsObjectIdHex(id) {
+		return errors.New("Invalid Id Hex")
+	}
+	return w.DbSession.DB("").C("redirects").RemoveId(bson.ObjectIdHex(id))
+}
+
+// Get one page of redirects
+func RedirectPage(q listing.Query, w *wrapper.Wrapper) ([]Redirect, listing.Page, error) {
+	rl := make([]Redirect, 0)
+	c := w.DbSession.DB("").C("redirects")
+	p, err := q.Run(c, &rl)
+	return rl, p, err
+}
+
+// Find the redirect for a url and the url to redirect to.
+func Match(u string, w *wrapper.Wrapper) (Redirect, string, error) {
+	return MatchDB(u, w.SiteConfig, w.DbSession.DB(""))
+}
+
+// Find the redirect for a url in a database, used outside of controllers.
+// Urls served by a published path are never redirected.  Exact redirects are
+// tried first, then prefix redirects from the longest prefix.
+func MatchDB(u string, s *configs.SiteConfig, db *mgo.Database) (Redirect, string, error) {
+	var r Redirect
+	published, err := paths.Published(u, s, db)
+	if err != nil {
+		return r, "", err
+	}
+	if published {
+		return r, "", mgo.ErrNotFound
+	}
+	start := time.Now()
+	rl := make([]Redirect, 0)
+	q := bson.M{"$or": []bson.M{
+		bson.M{"from": u, "prefix": false},
+		bson.M{"from": bson.M{"$in": prefixes(u)}, "prefix": true},
+	}}
+	err = db.C("redirects").Find(q).All(&rl)
+	metrics.DBDuration.Since(start, s.Name, "redirects")
+	if err != nil {
+		return r, "", err
+	}
+	found := false
+	for _, m := range rl {
+		if !m.Prefix {
+			return m, m.To, nil
+		}
+		if !found || len(m.From) > len(r.From) {
+			r = m
+			found = true
+		}
+	}
+	if !found {
+		return r, "", mgo.ErrNotFound
+	}
+	return r, rewrite(u, r.From, r.To), nil
+}
+
+// The url and every url above it, the urls a prefix redirect can match.
+func prefixes(u string) []string {
+	l := make([]string, 0)
+	for p := u; ; p = path.Dir(p) {
+		l = append(l, p)
+		if p == "/" || p == "." {
+			break
+		}
+	}
+	return l
+}
+
+// Replace the prefix from of a url with to, keeping the rest of the url.
+func rewrite(u string, from string, to string) string {
+	rest := strings.TrimPrefix(u, strings.TrimSuffix(from, "/"))
+	to = strings.TrimSuffix(to, "/") + strings.TrimSuffix(rest, "/")
+	if to == "" {
+		to = "/"
+	}
+	return to
+}
+
+// Add a redirect from a path's old url to its new one.
+// Redirects from the new url are removed so the path can be reached, and
+// redirects to the old url are pointed at the new one to avoid chains.
+func Renamed(from string, to string, prefix bool, w *wrapper.Wrapper) error {
+	c := w.DbSession.DB("").C("redirects")
+	_, err := c.RemoveAll(bson.M{"from": to})
+	if err != nil {
+		return err
+	}
+	_, err = c.UpdateAll(bson.M{"to": from}, bson.M{"$set": bson.M{"to": to}})
+	if err != nil {
+		return err
+	}
+	_, err = c.RemoveAll(bson.M{"from": from, "prefix": prefix})
+	if err != nil {
+		return err
+	}
+	r := NewRedirect()
+	r.From = from
+	r.To = to
+	r.Prefix = prefix
+	r.Automatic = true
+	return r.Save(w)
+}
+
+// Import redirects from CSV with the columns from, to, status and type
+// ("exact" or "prefix").  Status and type are optional, and a first row
+// starting with "from" is treated as a header.
+// Rows that fail are returned as errors, the other rows are imported.
+func ImportCSV(in io.Reader, w *wrapper.Wrapper) (int, []string) {
+	imported := 0
+	errs := make([]string, 0)
+	cr := csv.NewReader(in)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	for line := 1; ; line++ {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("line %d: %s", line, err.Error()))
+			break
+		}
+		if line == 1 && len(row) > 0 && strings.EqualFold(row[0], "from") {
+			continue
+		}
+		if len(row) < 2 {
+			errs = append(errs, fmt.Sprintf("line %d: from and to required", line))
+			continue
+		}
+		r := NewRedirect()
+		r.From = row[0]
+		r.To = row[1]
+		if len(row) > 2 && row[2] != "" {
+			r.Status, err = strconv.Atoi(row[2])
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("line %d: invalid status %s", line, row[2]))
+				continue
+			}
+		}
+		if len(row) > 3 {
+			r.Prefix = row[3] == "prefix"
+		}
+		var existing Redirect
+		err = w.DbSession.DB("").C("redirects").Find(bson.M{"from": r.From, "prefix": r.Prefix}).One(&existing)
+		if err == nil {
+			r.MongoId = existing.MongoId
+			r.Created = existing.Created
+		}
+		err = r.Save(w)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("line %d: %s", line, err.Error()))
+			continue
+		}
+		imported++
+	}
+	return imported, errs
+}
@@ -0,0 +1,48 @@
+package redirects
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPrefixes(t *testing.T) {
+	tests := []struct {
+		url      string
+		prefixes []string
+	}{
+		{"/", []string{"/"}},
+		{"/blog", []string{"/blog", "/"}},
+		{"/blog/2015/hello", []string{"/blog/2015/hello", "/blog/2015", "/blog", "/"}},
+	}
+	for _, test := range tests {
+		p := prefixes(test.url)
+		if !reflect.DeepEqual(p, test.prefixes) {
+			t.Errorf("%s: prefixes are %v, expected %v", test.url, p, test.prefixes)
+		}
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	tests := []struct {
+		url  string
+		from string
+		to   string
+		dest string
+	}{
+		{"/blog", "/blog", "/news", "/news"},
+		{"/blog/2015/hello", "/blog", "/news", "/news/2015/hello"},
+		{"/blog/2015/hello", "/blog", "/news/", "/news/2015/hello"},
+		{"/blog/2015/hello", "/blog/2015", "/archive", "/archive/hello"},
+		{"/blog/hello", "/blog", "/", "/hello"},
+		{"/blog", "/blog", "/", "/"},
+		{"/blog/hello", "/", "/old", "/old/blog/hello"},
+		{"/", "/", "/old", "/old"},
+		{"/blog/hello", "/blog", "https://example.com", "https://example.com/hello"},
+	}
+	for _, test := range tests {
+		dest := rewrite(test.url, test.from, test.to)
+		if dest != test.dest {
+			t.Errorf("%s from %s to %s: redirected to %s, expected %s", test.url, test.from, test.to, dest, test.dest)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+// References are a reverse index of reference fields in content elements,
+// so we can find which elements depend on another before it is deleted.
+package references
+
+import (
+	"github.com/mongolar/mongolar/wrapper"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// A single reference from one element to another
+type Reference struct {
+	MongoId bson.ObjectId `bson:"_id" json:"-"`
+	Source  string        `bson:"source" json:"source"`
+	Target  string        `bson:"target" json:"target"`
+	Field   string        `bson:"field" json:"field"`
+}
+
+// Replace all references from a source element, refs is a map of field paths to
+// the referenced element ids.
+func Set(source string, refs map[string][]string, w *wrapper.Wrapper) error {
+	err := DeleteSource(source, w)
+	if err != nil {
+		return err
+	}
+	c := w.DbSession.DB("").C("references")
+	for field, ids := range refs {
+		for _, id := range ids {
+			r := Reference{
+				MongoId: bson.NewObjectId(),
+				Source:  source,
+				Target:  id,
+				Field:   field,
+			}
+			err = c.Insert(r)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Delete all references from a source element.
+func DeleteSource(source string, w *wrapper.Wrapper) error {
+	c := w.DbSession.DB("").C("references")
+	_, err := c.RemoveAll(bson.M{"source": source})
+	return err
+}
+
+// Get all references to a target element.
+func Dependents(target string, w *wrapper.Wrapper) ([]Reference, error) {
+	rs := make([]Reference, 0)
+	c := w.DbSession.DB("").C("references")
+	err := c.Find(bson.M{"target": target}).All(&rs)
+	return rs, err
+}
+
+// Copy all references from one source element to another.
+func Copy(source string, target string, w *wrapper.Wrapper) error {
+	rs := make([]Reference, 0)
+	c := w.DbSession.DB("").C("references")
+	err := c.Find(bson.M{"source": source}).All(&rs)
+	if err != nil {
+		return err
+	}
+	for _, r := range rs {
+		r.MongoId = bson.NewObjectId()
+		r.Source = target
+		err = c.Insert(r)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,322 @@
+// Trash holds deleted paths and elements until they are restored or purged.
+// A trashed document is moved out of its collection so public controllers no
+// longer find it, and the positions it held in paths, wrappers and slugs are
+// kept so a restore can put it back where it was.
+// Items are purged by a TTL index after the site's TrashRetention.
+
+package trash
+
+import (
+	"errors"
+	"github.com/mongolar/mongolar/configs"
+	"github.com/mongolar/mongolar/listing"
+	"github.com/mongolar/mongolar/models/contenttypes"
+	"github.com/mongolar/mongolar/models/elements"
+	"github.com/mongolar/mongolar/models/paths"
+	"github.com/mongolar/mongolar/models/references"
+	"github.com/mongolar/mongolar/wrapper"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"strings"
+	"time"
+)
+
+// Days trashed items are kept when the site does not set TrashRetention
+const DefaultRetention = 30
+
+// A trashed path or element
+type Item struct {
+	MongoId    bson.ObjectId `bson:"_id" json:"id"`
+	Collection string        `bson:"collection" json:"collection"`
+	Title      string        `bson:"title" json:"title"`
+	Document   bson.M        `bson:"document" json:"document"`
+	Positions  []Position    `bson:"positions" json:"positions"`
+	Deleted    time.Time     `bson:"deleted" json:"deleted"`
+}
+
+// A place the trashed element was used.
+// Parents are paths or wrappers with an index in their elements, or slugs with the slug.
+type Position struct {
+	Collection string `bson:"collection" json:"collection"`
+	Parent     string `bson:"parent" json:"parent"`
+	Index      int    `bson:"index" json:"index"`
+	Slug       string `bson:"slug,omitempty" json:"slug,omitempty"`
+}
+
+// Sorts and filters available when listing the trash
+var Listing = listing.Definition{
+	Sorts: map[string]string{
+		"title":   "title",
+		"deleted": "deleted",
+	},
+	Filters: map[string]listing.Filter{
+		"title":      listing.Filter{Field: "title", Partial: true},
+		"collection": listing.Filter{Field: "collection"},
+	},
+}
+
+// How long trashed items of a site are kept.
+func Retention(s *configs.SiteConfig) time.Duration {
+	days := s.TrashRetention
+	if days <= 0 {
+		days = DefaultRetention
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// Create the TTL index that purges trashed items.  The index can not be
+// created again with another expiry, so when TrashRetention changed the
+// existing index is changed with collMod.
+func EnsureIndex(s *configs.SiteConfig, db *mgo.Database) error {
+	i := mgo.Index{
+		Key:         []string{"deleted"},
+		Background:  true,
+		ExpireAfter: Retention(s),
+	}
+	err := db.C("trash").EnsureIndex(i)
+	if err == nil {
+		return nil
+	}
+	if !strings.Contains(err.Error(), "different options") && !strings.Contains(err.Error(), "IndexOptionsConflict") {
+		return err
+	}
+	cmd := bson.D{
+		{Name: "collMod", Value: "trash"},
+		{Name: "index", Value: bson.M{
+			"keyPattern":         bson.M{"deleted": 1},
+			"expireAfterSeconds": int(Retention(s).Seconds()),
+		}},
+	}
+	return db.Run(cmd, nil)
+}
+
+// Move an element to the trash and remove it from its parents.
+func TrashElement(id string, w *wrapper.Wrapper) error {
+	if !bson.IsObjectIdHex(id) {
+		return errors.New("Invalid Id Hex")
+	}
+	var d bson.M
+	err := w.DbSession.DB("").C("elements").FindId(bson.ObjectIdHex(id)).One(&d)
+	if err != nil {
+		return err
+	}
+	ps, err := positions(id, w)
+	if err != nil {
+		return err
+	}
+	err = trash("elements", d, ps, w)
+	if err != nil {
+		return err
+	}
+	err = elements.Delete(id, w)
+	if err != nil {
+		return err
+	}
+	err = elements.WrapperDeleteAllChild(id, w)
+	if err != nil {
+		return err
+	}
+	err = elements.SlugDeleteAllChild(id, w)
+	if err != nil {
+		return err
+	}
+	err = paths.DeleteAllChild(id, w)
+	if err != nil {
+		return err
+	}
+	return references.DeleteSource(id, w)
+}
+
+// Move a path to the trash.
+func TrashPath(id string, w *wrapper.Wrapper) error {
+	if !bson.IsObjectIdHex(id) {
+		return errors.New("Invalid Id Hex")
+	}
+	var d bson.M
+	err := w.DbSession.DB("").C("paths").FindId(bson.ObjectIdHex(id)).One(&d)
+	if err != nil {
+		return err
+	}
+	err = trash("paths", d, nil, w)
+	if err != nil {
+		return err
+	}
+	return paths.Delete(id, w)
+}
+
+// Put a trashed item back in its collection and at its previous positions.
+// Parents that no longer exist are skipped.
+func Restore(id string, w *wrapper.Wrapper) error {
+	i, err := Load(id, w)
+	if err != nil {
+		return err
+	}
+	oid, ok := i.Document["_id"].(bson.ObjectId)
+	if !ok {
+		return errors.New("Trashed document has no valid id")
+	}
+	eid := oid.Hex()
+	c := w.DbSession.DB("").C(i.Collection)
+	// Trashing copies the document before removing it, a document that is
+	// still in its collection was never removed and keeps its positions.
+	n, err := c.FindId(oid).Count()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		err = c.Insert(i.Document)
+		if err != nil {
+			return err
+		}
+		for _, p := range i.Positions {
+			err = restorePosition(eid, p, w)
+			if err != nil && err != mgo.ErrNotFound {
+				restored(i.Collection, eid, w)
+				return err
+			}
+		}
+	}
+	restored(i.Collection, eid, w)
+	if i.Collection == "elements" && i.Document["controller"] == "content" {
+		err = restoreReferences(eid, w)
+		if err != nil {
+			return err
+		}
+	}
+	return Purge(id, w)
+}
+
+// Tell the caches and the change hooks a document is back.
+func restored(collection string, id string, w *wrapper.Wrapper) {
+	paths.Invalidate(w.SiteConfig)
+	if collection == "elements" {
+		elements.Invalidate(w, id)
+		return
+	}
+	p, err := paths.LoadPath(id, w)
+	if err == nil {
+		paths.Changed(p, w)
+	}
+}
+
+// Permanently delete a trashed item.
+func Purge(id string, w *wrapper.Wrapper) error {
+	if !bson.IsObjectIdHex(id) {
+		return errors.New("Invalid Id Hex")
+	}
+	return w.DbSession.DB("").C("trash").RemoveId(bson.ObjectIdHex(id))
+}
+
+// Get a trashed item by id.
+func Load(id string, w *wrapper.Wrapper) (Item, error) {
+	var i Item
+	if !bson.IsObjectIdHex(id) {
+		return i, errors.New("Invalid Id Hex")
+	}
+	err := w.DbSession.DB("").C("trash").FindId(bson.ObjectIdHex(id)).One(&i)
+	return i, err
+}
+
+// Get one page of trashed items
+func TrashPage(q listing.Query, w *wrapper.Wrapper) ([]Item, listing.Page, error) {
+	il := make([]Item, 0)
+	c := w.DbSession.DB("").C("trash")
+	p, err := q.Run(c, &il)
+	return il, p, err
+}
+
+func trash(collection string, d bson.M, ps []Position, w *wrapper.Wrapper) error {
+	title, _ := d["title"].(string)
+	if title == "" {
+		title, _ = d["path"].(string)
+	}
+	i := Item{
+		MongoId:    bson.NewObjectId(),
+		Collection: collection,
+		Title:      title,
+		Document:   d,
+		Positions:  ps,
+		Deleted:    time.Now(),
+	}
+	return w.DbSession.DB("").C("trash").Insert(i)
+}
+
+// Find every path, wrapper and slug an element is used in.
+func positions(id string, w *wrapper.Wrapper) ([]Position, error) {
+	ps := make([]Position, 0)
+	pl := make([]paths.Path, 0)
+	err := w.DbSession.DB("").C("paths").Find(bson.M{"elements": id}).All(&pl)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range pl {
+		for n, eid := range p.Elements {
+			if eid == id {
+				ps = append(ps, Position{Collection: "paths", Parent: p.MongoId.Hex(), Index: n})
+			}
+		}
+	}
+	c := w.DbSession.DB("").C("elements")
+	wl := make([]elements.WrapperElement, 0)
+	err = c.Find(bson.M{"controller": "wrapper", "controller_values.elements": id}).All(&wl)
+	if err != nil {
+		return nil, err
+	}
+	for _, we := range wl {
+		for n, eid := range we.Elements {
+			if eid == id {
+				ps = append(ps, Position{Collection: "elements", Parent: we.MongoId.Hex(), Index: n})
+			}
+		}
+	}
+	sl := make([]elements.SlugElement, 0)
+	err = c.Find(bson.M{"controller": "slug"}).All(&sl)
+	if err != nil {
+		return nil, err
+	}
+	for _, se := range sl {
+		for slug, eid := range se.Slugs {
+			if eid == id {
+				ps = append(ps, Position{Collection: "elements", Parent: se.MongoId.Hex(), Slug: slug})
+			}
+		}
+	}
+	return ps, nil
+}
+
+func restorePosition(id string, p Position, w *wrapper.Wrapper) error {
+	c := w.DbSession.DB("").C(p.Collection)
+	parent := bson.ObjectIdHex(p.Parent)
+	if p.Collection == "elements" {
+		defer elements.Invalidate(w, p.Parent)
+	}
+	if p.Slug != "" {
+		// Slugs that were reused while the element was in the trash are kept.
+		s := bson.M{"_id": parent, "controller_values." + p.Slug: bson.M{"$exists": false}}
+		return c.Update(s, elements.Stamp(bson.M{"$set": bson.M{"controller_values." + p.Slug: id}}))
+	}
+	field := "elements"
+	if p.Collection == "elements" {
+		field = "controller_values.elements"
+	}
+	push := bson.M{field: bson.M{"$each": []string{id}, "$position": p.Index}}
+	update := bson.M{"$push": push}
+	if p.Collection == "elements" {
+		update = elements.Stamp(update)
+	}
+	return c.UpdateId(parent, update)
+}
+
+// Rebuild the reference index of a restored content element.
+func restoreReferences(id string, w *wrapper.Wrapper) error {
+	ce, err := elements.LoadContentElement(id, w)
+	if err != nil {
+		return err
+	}
+	ct, err := contenttypes.LoadContentTypeT(ce.Type, w)
+	// Content whose type was deleted has no references to index.
+	if err != nil {
+		return nil
+	}
+	return references.Set(id, ct.References(ce.Content), w)
+}
@@ -0,0 +1,25 @@
+package trash
+
+import (
+	"github.com/mongolar/mongolar/configs"
+	"testing"
+	"time"
+)
+
+func TestRetention(t *testing.T) {
+	tests := []struct {
+		days      int
+		retention time.Duration
+	}{
+		{0, 30 * 24 * time.Hour},
+		{-1, 30 * 24 * time.Hour},
+		{1, 24 * time.Hour},
+		{90, 90 * 24 * time.Hour},
+	}
+	for _, test := range tests {
+		r := Retention(&configs.SiteConfig{TrashRetention: test.days})
+		if r != test.retention {
+			t.Errorf("%d days: retention is %s, expected %s", test.days, r, test.retention)
+		}
+	}
+}
@@ -0,0 +1,312 @@
+// Tree works on the structure of a site, the paths, wrapper and slug elements
+// that hold other elements.
+
+package tree
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mongolar/mongolar/models/elements"
+	"github.com/mongolar/mongolar/models/paths"
+	"github.com/mongolar/mongolar/wrapper"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"strings"
+)
+
+// Parent types
+const (
+	Path    = "paths"
+	Wrapper = "wrapper"
+	Slug    = "slug"
+)
+
+var (
+	ErrCycle    = errors.New("An element can not be moved into itself")
+	ErrNotChild = errors.New("Element is not a child of the source")
+	ErrChanged  = errors.New("The parent was changed by someone else, please try again")
+	ErrSlugUsed = errors.New("Slug is already in use")
+)
+
+// A position in a parent.
+// Paths and wrappers use the index in their elements, slugs use the slug.
+type Parent struct {
+	Type  string `json:"type"`
+	Id    string `json:"id"`
+	Index int    `json:"index"`
+	Slug  string `json:"slug,omitempty"`
+}
+
+// Move an element from one parent to a position in another.
+// The element is added to the target before it is removed from the source,
+// if the removal fails the target is restored so the element is never lost.
+func Move(id string, from Parent, to Parent, w *wrapper.Wrapper) error {
+	if !bson.IsObjectIdHex(id) {
+		return errors.New("Invalid Id Hex")
+	}
+	err := from.validate()
+	if err != nil {
+		return err
+	}
+	err = to.validate()
+	if err != nil {
+		return err
+	}
+	if to.Type != Path {
+		contains, err := Contains(id, to.Id, w)
+		if err != nil {
+			return err
+		}
+		if contains {
+			return ErrCycle
+		}
+	}
+	if from.Type != Slug && from.Type == to.Type && from.Id == to.Id {
+		return reorder(id, from, to, w)
+	}
+	index, err := insert(id, to, w)
+	if err != nil {
+		return err
+	}
+	err = remove(id, from, w)
+	if err != nil {
+		// Only the inserted occurrence is taken back, the element may already
+		// have been in the target.
+		to.Index = index
+		rerr := undo(id, to, w)
+		if rerr != nil {
+			return fmt.Errorf("%s, and it could not be removed from the target again: %s", err.Error(), rerr.Error())
+		}
+		return err
+	}
+	return nil
+}
+
+// Check if an element is, or contains, another element through wrappers and slugs.
+func Contains(ancestor string, id string, w *wrapper.Wrapper) (bool, error) {
+	seen := make(map[string]bool)
+	queue := []string{ancestor}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if current == id {
+			return true, nil
+		}
+		if seen[current] || !bson.IsObjectIdHex(current) {
+			continue
+		}
+		seen[current] = true
+		children, err := Children(current, w)
+		if err != nil {
+			return false, err
+		}
+		queue = append(queue, children...)
+	}
+	return false, nil
+}
+
+// The children of a wrapper or slug element, other elements have none.
+func Children(id string, w *wrapper.Wrapper) ([]string, error) {
+	children := make([]string, 0)
+	var d struct {
+		Controller string   `bson:"controller"`
+		Values     bson.Raw `bson:"controller_values"`
+	}
+	err := w.DbSession.DB("").C("elements").FindId(bson.ObjectIdHex(id)).One(&d)
+	if err != nil {
+		if err == mgo.ErrNotFound {
+			return children, nil
+		}
+		return nil, err
+	}
+	switch d.Controller {
+	case "wrapper":
+		var v struct {
+			Elements []string `bson:"elements"`
+		}
+		d.Values.Unmarshal(&v)
+		children = append(children, v.Elements...)
+	case "slug":
+		v := make(map[string]string)
+		d.Values.Unmarshal(&v)
+		for _, eid := range v {
+			children = append(children, eid)
+		}
+	}
+	return children, nil
+}
+
+func (p Parent) validate() error {
+	if p.Type != Path && p.Type != Wrapper && p.Type != Slug {
+		return errors.New("Unknown parent type " + p.Type)
+	}
+	if !bson.IsObjectIdHex(p.Id) {
+		return errors.New("Invalid Id Hex")
+	}
+	if p.Type == Slug && (p.Slug == "" || strings.ContainsAny(p.Slug, ".$")) {
+		return errors.New("Invalid slug")
+	}
+	return nil
+}
+
+// The collection, selector and field holding the elements of a path or wrapper.
+func (p Parent) list() (string, bson.M, string) {
+	if p.Type == Path {
+		return "paths", bson.M{"_id": bson.ObjectIdHex(p.Id)}, "elements"
+	}
+	return "elements", bson.M{"_id": bson.ObjectIdHex(p.Id), "controller": "wrapper"}, "controller_values.elements"
+}
+
+func (p Parent) load(w *wrapper.Wrapper) ([]string, error) {
+	collection, s, field := p.list()
+	var d bson.M
+	err := w.DbSession.DB("").C(collection).Find(s).Select(bson.M{field: 1}).One(&d)
+	if err != nil {
+		return nil, err
+	}
+	v := interface{}(d)
+	for _, k := range strings.Split(field, ".") {
+		m, _ := v.(bson.M)
+		v = m[k]
+	}
+	l := make([]string, 0)
+	items, _ := v.([]interface{})
+	for _, item := range items {
+		if eid, ok := item.(string); ok {
+			l = append(l, eid)
+		}
+	}
+	return l, nil
+}
+
+// Replace the elements of a path or wrapper only if they were not changed since
+// they were loaded.
+func (p Parent) save(old []string, l []string, w *wrapper.Wrapper) error {
+	collection, s, field := p.list()
+	s[field] = old
+	if len(old) == 0 {
+		s[field] = bson.M{"$in": []interface{}{nil, []string{}}}
+	}
+	update := bson.M{"$set": bson.M{field: l}}
+	if p.Type != Path {
+		update = elements.Stamp(update)
+	}
+	err := w.DbSession.DB("").C(collection).Update(s, update)
+	if p.Type == Path {
+		paths.Invalidate(w.SiteConfig)
+	} else {
+		elements.Invalidate(w, p.Id)
+	}
+	if err == mgo.ErrNotFound {
+		return ErrChanged
+	}
+	return err
+}
+
+// Move an element within the elements of one path or wrapper.
+func reorder(id string, from Parent, to Parent, w *wrapper.Wrapper) error {
+	old, err := from.load(w)
+	if err != nil {
+		return err
+	}
+	l, err := without(old, id, from.Index)
+	if err != nil {
+		return err
+	}
+	return from.save(old, with(l, id, to.Index), w)
+}
+
+// Add the element to a parent, returns the index it was inserted at.
+func insert(id string, p Parent, w *wrapper.Wrapper) (int, error) {
+	if p.Type == Slug {
+		c := w.DbSession.DB("").C("elements")
+		s := bson.M{"_id": bson.ObjectIdHex(p.Id), "controller": "slug", "controller_values." + p.Slug: bson.M{"$exists": false}}
+		err := c.Update(s, elements.Stamp(bson.M{"$set": bson.M{"controller_values." + p.Slug: id}}))
+		elements.Invalidate(w, p.Id)
+		if err == mgo.ErrNotFound {
+			return 0, ErrSlugUsed
+		}
+		return 0, err
+	}
+	old, err := p.load(w)
+	if err != nil {
+		return 0, err
+	}
+	index := position(old, p.Index)
+	return index, p.save(old, with(old, id, index), w)
+}
+
+// Take back an insert, the element has to still be at p.Index.
+func undo(id string, p Parent, w *wrapper.Wrapper) error {
+	if p.Type == Slug {
+		return remove(id, p, w)
+	}
+	old, err := p.load(w)
+	if err != nil {
+		return err
+	}
+	if p.Index >= len(old) || old[p.Index] != id {
+		return ErrChanged
+	}
+	l := make([]string, 0)
+	l = append(l, old[:p.Index]...)
+	return p.save(old, append(l, old[p.Index+1:]...), w)
+}
+
+func remove(id string, p Parent, w *wrapper.Wrapper) error {
+	if p.Type == Slug {
+		c := w.DbSession.DB("").C("elements")
+		s := bson.M{"_id": bson.ObjectIdHex(p.Id), "controller": "slug", "controller_values." + p.Slug: id}
+		err := c.Update(s, elements.Stamp(bson.M{"$unset": bson.M{"controller_values." + p.Slug: ""}}))
+		elements.Invalidate(w, p.Id)
+		if err == mgo.ErrNotFound {
+			return ErrNotChild
+		}
+		return err
+	}
+	old, err := p.load(w)
+	if err != nil {
+		return err
+	}
+	l, err := without(old, id, p.Index)
+	if err != nil {
+		return err
+	}
+	return p.save(old, l, w)
+}
+
+// Remove the element at index, or its first occurrence if it is not at index.
+func without(l []string, id string, index int) ([]string, error) {
+	if index < 0 || index >= len(l) || l[index] != id {
+		index = -1
+		for i, eid := range l {
+			if eid == id {
+				index = i
+				break
+			}
+		}
+	}
+	if index < 0 {
+		return nil, ErrNotChild
+	}
+	n := make([]string, 0)
+	n = append(n, l[:index]...)
+	return append(n, l[index+1:]...), nil
+}
+
+// Insert the element at index, out of range indexes append it.
+func with(l []string, id string, index int) []string {
+	index = position(l, index)
+	n := make([]string, 0)
+	n = append(n, l[:index]...)
+	n = append(n, id)
+	return append(n, l[index:]...)
+}
+
+// The index an element is inserted at, out of range indexes are the end.
+func position(l []string, index int) int {
+	if index < 0 || index > len(l) {
+		return len(l)
+	}
+	return index
+}
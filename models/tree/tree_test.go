@@ -0,0 +1,66 @@
+package tree
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWith(t *testing.T) {
+	tests := []struct {
+		list  []string
+		index int
+		with  []string
+	}{
+		{[]string{}, 0, []string{"x"}},
+		{[]string{"a", "b"}, 0, []string{"x", "a", "b"}},
+		{[]string{"a", "b"}, 1, []string{"a", "x", "b"}},
+		{[]string{"a", "b"}, 2, []string{"a", "b", "x"}},
+		{[]string{"a", "b"}, 5, []string{"a", "b", "x"}},
+		{[]string{"a", "b"}, -1, []string{"a", "b", "x"}},
+	}
+	for _, test := range tests {
+		l := with(test.list, "x", test.index)
+		if !reflect.DeepEqual(l, test.with) {
+			t.Errorf("%v at %d: %v, expected %v", test.list, test.index, l, test.with)
+		}
+		if i := position(test.list, test.index); l[i] != "x" {
+			t.Errorf("%v at %d: position %d does not hold the element", test.list, test.index, i)
+		}
+	}
+}
+
+func TestWithout(t *testing.T) {
+	tests := []struct {
+		list    []string
+		index   int
+		without []string
+		err     error
+	}{
+		{[]string{"x", "a", "x"}, 2, []string{"x", "a"}, nil},
+		{[]string{"x", "a", "x"}, 0, []string{"a", "x"}, nil},
+		{[]string{"x", "a", "x"}, 1, []string{"a", "x"}, nil},
+		{[]string{"a", "x"}, 5, []string{"a"}, nil},
+		{[]string{"a", "x"}, -1, []string{"a"}, nil},
+		{[]string{"a", "b"}, 0, nil, ErrNotChild},
+		{[]string{}, 0, nil, ErrNotChild},
+	}
+	for _, test := range tests {
+		l, err := without(test.list, "x", test.index)
+		if err != test.err {
+			t.Errorf("%v at %d: error %v, expected %v", test.list, test.index, err, test.err)
+			continue
+		}
+		if !reflect.DeepEqual(l, test.without) {
+			t.Errorf("%v at %d: %v, expected %v", test.list, test.index, l, test.without)
+		}
+	}
+}
+
+func TestListsAreCopied(t *testing.T) {
+	l := []string{"a", "x", "b"}
+	without(l, "x", 1)
+	with(l[:1], "y", 1)
+	if !reflect.DeepEqual(l, []string{"a", "x", "b"}) {
+		t.Errorf("list changed to %v", l)
+	}
+}
@@ -0,0 +1,170 @@
+package tree
+
+import (
+	"github.com/mongolar/mongolar/models/elements"
+	"github.com/mongolar/mongolar/models/paths"
+	"github.com/mongolar/mongolar/wrapper"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// A wrapper or slug element in an ancestor chain
+type Ancestor struct {
+	Id         string `json:"id"`
+	Title      string `json:"title"`
+	Controller string `json:"controller"`
+	Slug       string `json:"slug,omitempty"`
+}
+
+// One place an element is used, from the path down to the element's parent.
+// Chains of elements that are not on any path have no path.
+type Chain struct {
+	Path      *paths.Path `json:"path,omitempty"`
+	Ancestors []Ancestor  `json:"ancestors"`
+}
+
+// Find every path and ancestor chain containing an element.
+func WhereUsed(id string, w *wrapper.Wrapper) ([]Chain, error) {
+	u := &usage{
+		w:       w,
+		parents: make(map[string][]Ancestor),
+		paths:   make(map[string][]paths.Path),
+		chains:  make(map[string][]Chain),
+		walking: make(map[string]bool),
+	}
+	err := u.loadSlugs()
+	if err != nil {
+		return nil, err
+	}
+	err = u.load(id)
+	if err != nil {
+		return nil, err
+	}
+	chains := make([]Chain, 0)
+	for _, c := range u.up(id) {
+		// An element that is not used anywhere is not a chain.
+		if c.Path != nil || len(c.Ancestors) > 0 {
+			chains = append(chains, c)
+		}
+	}
+	return chains, nil
+}
+
+// The parents of the ancestors of an element are loaded once, the chains are
+// built from them in memory.
+type usage struct {
+	w       *wrapper.Wrapper
+	parents map[string][]Ancestor
+	paths   map[string][]paths.Path
+	chains  map[string][]Chain
+	walking map[string]bool
+}
+
+// Slug values can not be queried so all slugs are loaded once.
+func (u *usage) loadSlugs() error {
+	sl := make([]elements.SlugElement, 0)
+	err := u.w.DbSession.DB("").C("elements").Find(bson.M{"controller": "slug"}).All(&sl)
+	if err != nil {
+		return err
+	}
+	for _, se := range sl {
+		for slug, eid := range se.Slugs {
+			a := Ancestor{Id: se.MongoId.Hex(), Title: se.Title, Controller: "slug", Slug: slug}
+			u.parents[eid] = append(u.parents[eid], a)
+		}
+	}
+	return nil
+}
+
+// Load the paths and wrappers holding an element and its ancestors, with one
+// query for each of them per level.
+func (u *usage) load(id string) error {
+	seen := map[string]bool{id: true}
+	level := []string{id}
+	for len(level) > 0 {
+		current := make(map[string]bool)
+		for _, eid := range level {
+			current[eid] = true
+		}
+		pl := make([]paths.Path, 0)
+		err := u.w.DbSession.DB("").C("paths").Find(bson.M{"elements": bson.M{"$in": level}}).All(&pl)
+		if err != nil {
+			return err
+		}
+		for _, p := range pl {
+			for _, eid := range distinct(p.Elements) {
+				if current[eid] {
+					u.paths[eid] = append(u.paths[eid], p)
+				}
+			}
+		}
+		wl := make([]elements.WrapperElement, 0)
+		s := bson.M{"controller": "wrapper", "controller_values.elements": bson.M{"$in": level}}
+		err = u.w.DbSession.DB("").C("elements").Find(s).All(&wl)
+		if err != nil {
+			return err
+		}
+		for _, we := range wl {
+			a := Ancestor{Id: we.MongoId.Hex(), Title: we.Title, Controller: "wrapper"}
+			for _, eid := range distinct(we.Elements) {
+				if current[eid] {
+					u.parents[eid] = append(u.parents[eid], a)
+				}
+			}
+		}
+		next := make([]string, 0)
+		for _, eid := range level {
+			for _, a := range u.parents[eid] {
+				if !seen[a.Id] {
+					seen[a.Id] = true
+					next = append(next, a.Id)
+				}
+			}
+		}
+		level = next
+	}
+	return nil
+}
+
+// The chains from a path down to the parent of an element, each element's
+// chains are built once.  Elements that are not on any path end a chain
+// without a path.
+func (u *usage) up(id string) []Chain {
+	if chains, ok := u.chains[id]; ok {
+		return chains
+	}
+	u.walking[id] = true
+	chains := make([]Chain, 0)
+	for i := range u.paths[id] {
+		chains = append(chains, Chain{Path: &u.paths[id][i], Ancestors: make([]Ancestor, 0)})
+	}
+	for _, a := range u.parents[id] {
+		// Parents that contain their own ancestors are never followed.
+		if u.walking[a.Id] {
+			continue
+		}
+		for _, c := range u.up(a.Id) {
+			ancestors := make([]Ancestor, 0, len(c.Ancestors)+1)
+			ancestors = append(ancestors, c.Ancestors...)
+			chains = append(chains, Chain{Path: c.Path, Ancestors: append(ancestors, a)})
+		}
+	}
+	if len(u.paths[id]) == 0 && len(u.parents[id]) == 0 {
+		chains = append(chains, Chain{Ancestors: make([]Ancestor, 0)})
+	}
+	delete(u.walking, id)
+	u.chains[id] = chains
+	return chains
+}
+
+// Elements used more than once in a parent are listed once.
+func distinct(ids []string) []string {
+	l := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			l = append(l, id)
+		}
+	}
+	return l
+}
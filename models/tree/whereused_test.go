@@ -0,0 +1,51 @@
+package tree
+
+import (
+	"github.com/mongolar/mongolar/models/paths"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Chains written as "path > ancestor > ancestor", "-" for no path.
+func TestUp(t *testing.T) {
+	u := &usage{
+		parents: map[string][]Ancestor{
+			"e":  []Ancestor{Ancestor{Id: "w1"}, Ancestor{Id: "w2"}},
+			"w1": []Ancestor{Ancestor{Id: "w3"}},
+			"w2": []Ancestor{Ancestor{Id: "w3"}, Ancestor{Id: "s1", Slug: "a"}},
+			"w3": []Ancestor{Ancestor{Id: "e"}},
+		},
+		paths: map[string][]paths.Path{
+			"e":  []paths.Path{paths.Path{Path: "/e"}},
+			"w3": []paths.Path{paths.Path{Path: "/"}, paths.Path{Path: "/home"}},
+		},
+		chains:  make(map[string][]Chain),
+		walking: make(map[string]bool),
+	}
+	expected := []string{
+		"/e",
+		"/ > w3 > w1",
+		"/home > w3 > w1",
+		"/ > w3 > w2",
+		"/home > w3 > w2",
+		"- > s1 > w2",
+	}
+	chains := make([]string, 0)
+	for _, c := range u.up("e") {
+		parts := []string{"-"}
+		if c.Path != nil {
+			parts[0] = c.Path.Path
+		}
+		for _, a := range c.Ancestors {
+			parts = append(parts, a.Id)
+		}
+		chains = append(chains, strings.Join(parts, " > "))
+	}
+	if !reflect.DeepEqual(chains, expected) {
+		t.Errorf("chains are %v, expected %v", chains, expected)
+	}
+	if len(u.chains["w3"]) != 2 {
+		t.Errorf("the chains of w3 are not kept for its other children")
+	}
+}
@@ -6,18 +6,35 @@
 package main
 
 import (
+	"fmt"
 	"github.com/mongolar/mongolar/admin"
 	"github.com/mongolar/mongolar/basecontrollers"
+	"github.com/mongolar/mongolar/commands"
 	"github.com/mongolar/mongolar/configs"
 	"github.com/mongolar/mongolar/controller"
+	"github.com/mongolar/mongolar/health"
+	"github.com/mongolar/mongolar/metrics"
+	"github.com/mongolar/mongolar/models/elements"
+	"github.com/mongolar/mongolar/models/trash"
 	"github.com/mongolar/mongolar/oauthlogin"
+	"github.com/mongolar/mongolar/purge"
 	"github.com/mongolar/mongolar/router"
 	"gopkg.in/mgo.v2"
+	"log"
+	"net"
 	"net/http"
+	"os"
 	"time"
 )
 
 func main() {
+	purge.Register()
+	if len(os.Args) > 1 {
+		cmds := commands.NewMap()
+		commands.GetCommandMap(cmds)
+		Run(cmds, os.Args[1:])
+		return
+	}
 	cm := controller.NewMap()
 	basecontrollers.GetControllerMap(cm)
 	admin.GetControllerMap(cm)
@@ -25,11 +42,44 @@ func main() {
 	Serve(cm)
 }
 
+// Run a command line command instead of serving sites.
+func Run(cmds commands.CommandMap, args []string) {
+	c, _ := configs.New()
+	EnsureIndexes(c)
+	err := cmds.Run(c.SitesMap, args)
+	purge.Wait()
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
 func Serve(cm controller.ControllerMap) {
 	c, port := configs.New()
 	EnsureIndexes(c)
+	for _, site_config := range c.SitesMap {
+		go elements.Listen(site_config)
+	}
+	if configs.ServerConfig.AdminPort != "" {
+		go ServeAdmin(configs.ServerConfig.AdminAddress, configs.ServerConfig.AdminPort, c.SitesMap)
+	}
 	HostSwitch := router.New(c.Aliases, c.SitesMap, cm)
-	http.ListenAndServe(":"+port, HostSwitch)
+	// Health checks are answered for any host, everything else is routed by host.
+	err := http.ListenAndServe(":"+port, health.Handler(HostSwitch, c.SitesMap))
+	if err != nil {
+		log.Fatalf("Unable to serve port %s: %s", port, err.Error())
+	}
+}
+
+// Serve metrics and health checks for every site on the admin port, it is
+// bound to AdminAddress so it is not reachable from outside by default.
+func ServeAdmin(address string, port string, sm configs.SitesMap) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	health.Handle(mux, sm)
+	err := http.ListenAndServe(net.JoinHostPort(address, port), mux)
+	if err != nil {
+		log.Fatalf("Unable to serve admin port %s: %s", port, err.Error())
+	}
 }
 
 func EnsureIndexes(configs *configs.Configs) {
@@ -56,6 +106,14 @@ func EnsureIndexes(configs *configs.Configs) {
 		}
 		c = db_session.DB("").C("paths")
 		c.EnsureIndex(i)
+		i = mgo.Index{
+			Key:        []string{"pattern", "status", "segments"},
+			Unique:     false,
+			DropDups:   false,
+			Background: true,
+			Sparse:     false,
+		}
+		c.EnsureIndex(i)
 		i = mgo.Index{
 			Key:        []string{"id", "type"},
 			Unique:     true,
@@ -76,5 +134,41 @@ func EnsureIndexes(configs *configs.Configs) {
 		}
 		c = db_session.DB("").C("forms")
 		c.EnsureIndex(i)
+		i = mgo.Index{
+			Key:        []string{"target"},
+			Unique:     false,
+			DropDups:   false,
+			Background: true,
+			Sparse:     false,
+		}
+		c = db_session.DB("").C("references")
+		c.EnsureIndex(i)
+		i = mgo.Index{
+			Key:        []string{"source"},
+			Unique:     false,
+			DropDups:   false,
+			Background: true,
+			Sparse:     false,
+		}
+		c.EnsureIndex(i)
+		err := trash.EnsureIndex(site_config, db_session.DB(""))
+		if err != nil {
+			errmessage := fmt.Sprintf("Unable to create the trash index: %s", err.Error())
+			site_config.Logger.Error(errmessage)
+		}
+		i = mgo.Index{
+			Key:        []string{"from", "prefix"},
+			Unique:     false,
+			DropDups:   false,
+			Background: true,
+			Sparse:     false,
+		}
+		c = db_session.DB("").C("redirects")
+		c.EnsureIndex(i)
+		err = elements.EnsureInvalidations(site_config)
+		if err != nil {
+			errmessage := fmt.Sprintf("Unable to create element invalidations: %s", err.Error())
+			site_config.Logger.Error(errmessage)
+		}
 	}
 }
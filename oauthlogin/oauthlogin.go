@@ -46,12 +46,16 @@ func StateString() string {
 }
 
 func (l *LoginMap) Login(w *wrapper.Wrapper) {
+	if len(w.APIParams) == 0 {
+		w.Error(403, "Forbidden")
+		return
+	}
 	if controller, ok := l.Controllers[w.APIParams[0]]; ok {
 		w.Shift()
 		controller(w)
 		return
 	}
-	http.Error(w.Writer, "Forbidden", 403)
+	w.Error(403, "Forbidden")
 	return
 }
 
@@ -90,7 +94,7 @@ func (lo *LoginMap) Callback(w *wrapper.Wrapper) {
 			login := lo.Logins[w.APIParams[0]]
 			if lo.State != s {
 				errmessage := fmt.Sprintf("Invalid oauth state, expected %s, got %s", lo.State, s)
-				w.SiteConfig.Logger.Error(errmessage)
+				w.Logger.Error(errmessage)
 				http.Redirect(w.Writer, w.Request, loginurls["failure"], 301)
 				return
 			}
@@ -99,7 +103,7 @@ func (lo *LoginMap) Callback(w *wrapper.Wrapper) {
 			token, err := login.GetToken(code)
 			if err != nil {
 				errmessage := fmt.Sprintf("Exchange() failed with %s", err.Error())
-				w.SiteConfig.Logger.Error(errmessage)
+				w.Logger.Error(errmessage)
 				http.Redirect(w.Writer, w.Request, loginurls["failure"], 301)
 				return
 			}
@@ -107,21 +111,21 @@ func (lo *LoginMap) Callback(w *wrapper.Wrapper) {
 			err = u.Set(w)
 			if err != nil {
 				errmessage := fmt.Sprintf("Unable to set user: %s", err.Error())
-				w.SiteConfig.Logger.Error(errmessage)
+				w.Logger.Error(errmessage)
 				http.Redirect(w.Writer, w.Request, loginurls["failure"], 301)
 				return
 			}
 			err = w.SetSessionValue("user_id", u.MongoId)
 			if err != nil {
 				errmessage := fmt.Sprintf("Unable to set user id on session: %s", err.Error())
-				w.SiteConfig.Logger.Error(errmessage)
+				w.Logger.Error(errmessage)
 				http.Redirect(w.Writer, w.Request, loginurls["failure"], 301)
 				return
 			}
 			err = w.SetSessionValue("token", token)
 			if err != nil {
 				errmessage := fmt.Sprintf("Unable to set token on session: %s", err.Error())
-				w.SiteConfig.Logger.Error(errmessage)
+				w.Logger.Error(errmessage)
 				http.Redirect(w.Writer, w.Request, loginurls["failure"], 301)
 				return
 			}
@@ -129,7 +133,7 @@ func (lo *LoginMap) Callback(w *wrapper.Wrapper) {
 			return
 		}
 	}
-	http.Error(w.Writer, "Forbidden", 403)
+	w.Error(403, "Forbidden")
 	return
 }
 
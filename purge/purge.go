@@ -0,0 +1,169 @@
+// Purge tells caches in front of mongolar, like Varnish or a CDN, to drop
+// responses when elements and paths change.
+// Changed elements purge their own api urls, the api urls of the wrappers and
+// slugs holding them and the urls of the paths they are on.  Purges are sent
+// in the background and retried, failures are logged.
+
+package purge
+
+import (
+	"fmt"
+	"github.com/mongolar/mongolar/configs"
+	"github.com/mongolar/mongolar/models/elements"
+	"github.com/mongolar/mongolar/models/paths"
+	"github.com/mongolar/mongolar/models/tree"
+	"github.com/mongolar/mongolar/wrapper"
+	"sync"
+	"time"
+)
+
+// Attempts used when the site does not set PurgeRetries
+const DefaultRetries = 3
+
+var pending sync.WaitGroup
+
+// A url to purge, prefix targets also purge every url below them.
+type Target struct {
+	Path   string `json:"path"`
+	Prefix bool   `json:"prefix"`
+}
+
+// Sends targets to an endpoint of a type.
+type Sender func(s *configs.SiteConfig, e configs.PurgeEndpoint, targets []Target) error
+
+// Senders by endpoint type, add to this to support other caches.
+var Senders = map[string]Sender{
+	"purge": SendPurge,
+	"ban":   SendBan,
+	"http":  SendHTTP,
+	"log":   SendLog,
+}
+
+// Purge caches when elements or paths change, call once at startup.
+func Register() {
+	elements.OnChange(elementsChanged)
+	paths.OnChange(pathChanged)
+}
+
+func elementsChanged(ids []string, all bool, w *wrapper.Wrapper) {
+	if len(w.SiteConfig.PurgeEndpoints) == 0 {
+		return
+	}
+	if all {
+		Send(w.SiteConfig, []Target{Target{Path: "/", Prefix: true}})
+		return
+	}
+	targets := make([]Target, 0)
+	for _, id := range ids {
+		t, err := ElementTargets(id, w)
+		if err != nil {
+			errmessage := fmt.Sprintf("Unable to find urls to purge for %s : %s", id, err.Error())
+			w.Logger.Error(errmessage)
+		}
+		targets = append(targets, t...)
+	}
+	Send(w.SiteConfig, targets)
+}
+
+func pathChanged(p paths.Path, w *wrapper.Wrapper) {
+	if len(w.SiteConfig.PurgeEndpoints) == 0 {
+		return
+	}
+	Send(w.SiteConfig, []Target{PathTarget(p)})
+}
+
+// The urls affected by a change to an element.
+// Deleted elements purge the url of every element controller for their id.
+func ElementTargets(id string, w *wrapper.Wrapper) ([]Target, error) {
+	targets := make([]Target, 0)
+	e, err := elements.LoadElement(id, w)
+	if err != nil {
+		for _, c := range w.SiteConfig.ElementControllers {
+			targets = append(targets, apiTarget(c, id, w.SiteConfig))
+		}
+		return targets, nil
+	}
+	targets = append(targets, apiTarget(e.Controller, id, w.SiteConfig))
+	chains, err := tree.WhereUsed(id, w)
+	if err != nil {
+		return targets, err
+	}
+	for _, c := range chains {
+		for _, a := range c.Ancestors {
+			targets = append(targets, apiTarget(a.Controller, a.Id, w.SiteConfig))
+		}
+		if c.Path != nil {
+			targets = append(targets, PathTarget(*c.Path))
+		}
+	}
+	return targets, nil
+}
+
+// The page url of a path, wildcard and pattern paths purge everything below them.
+func PathTarget(p paths.Path) Target {
+	if paths.IsPattern(p.Path) {
+		return Target{Path: paths.StaticPrefix(p.Path), Prefix: true}
+	}
+	return Target{Path: p.Path, Prefix: p.Wildcard}
+}
+
+// Api urls are purged with their query strings, like content?expand=1.
+func apiTarget(controller string, id string, s *configs.SiteConfig) Target {
+	return Target{Path: fmt.Sprintf("/%s/%s/%s", s.APIEndPoint, controller, id), Prefix: true}
+}
+
+// Send targets to every endpoint of a site in the background.
+func Send(s *configs.SiteConfig, targets []Target) {
+	targets = unique(targets)
+	if len(targets) == 0 {
+		return
+	}
+	for _, e := range s.PurgeEndpoints {
+		sender, ok := Senders[e.Type]
+		if !ok {
+			errmessage := fmt.Sprintf("Unknown purge endpoint type %s", e.Type)
+			s.Logger.Error(errmessage)
+			continue
+		}
+		pending.Add(1)
+		go retry(s, e, sender, targets)
+	}
+}
+
+// Wait for purges in progress, used before command line tools exit.
+func Wait() {
+	pending.Wait()
+}
+
+// Attempt a purge with increasing waits between attempts.
+func retry(s *configs.SiteConfig, e configs.PurgeEndpoint, sender Sender, targets []Target) {
+	defer pending.Done()
+	retries := s.PurgeRetries
+	if retries <= 0 {
+		retries = DefaultRetries
+	}
+	var err error
+	for attempt := 1; attempt <= retries; attempt++ {
+		err = sender(s, e, targets)
+		if err == nil {
+			return
+		}
+		errmessage := fmt.Sprintf("Purge of %s failed, attempt %d of %d : %s", e.URL, attempt, retries, err.Error())
+		s.Logger.Warn(errmessage)
+		time.Sleep(time.Duration(attempt*attempt) * time.Second)
+	}
+	errmessage := fmt.Sprintf("Gave up purging %v from %s : %s", targets, e.URL, err.Error())
+	s.Logger.Error(errmessage)
+}
+
+func unique(targets []Target) []Target {
+	seen := make(map[Target]bool)
+	u := make([]Target, 0)
+	for _, t := range targets {
+		if !seen[t] {
+			seen[t] = true
+			u = append(u, t)
+		}
+	}
+	return u
+}
@@ -0,0 +1,101 @@
+package purge
+
+import (
+	"github.com/mongolar/mongolar/configs"
+	"github.com/mongolar/mongolar/models/paths"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestPathTarget(t *testing.T) {
+	tests := []struct {
+		path   paths.Path
+		target Target
+	}{
+		{paths.Path{Path: "/about"}, Target{Path: "/about"}},
+		{paths.Path{Path: "/blog", Wildcard: true}, Target{Path: "/blog", Prefix: true}},
+		{paths.Path{Path: "/blog/:year/:slug"}, Target{Path: "/blog", Prefix: true}},
+	}
+	for _, test := range tests {
+		target := PathTarget(test.path)
+		if target != test.target {
+			t.Errorf("%s: target is %+v, expected %+v", test.path.Path, target, test.target)
+		}
+	}
+}
+
+func TestUnique(t *testing.T) {
+	targets := []Target{{Path: "/a"}, {Path: "/a", Prefix: true}, {Path: "/a"}}
+	expected := []Target{{Path: "/a"}, {Path: "/a", Prefix: true}}
+	if u := unique(targets); !reflect.DeepEqual(u, expected) {
+		t.Errorf("unique targets are %v, expected %v", u, expected)
+	}
+}
+
+func TestSendBan(t *testing.T) {
+	bans := make([]http.Header, 0)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "BAN" {
+			t.Errorf("method is %s, expected BAN", r.Method)
+		}
+		bans = append(bans, r.Header)
+	}))
+	defer ts.Close()
+	s := &configs.SiteConfig{Aliases: []string{"example.com", "www.example.com"}}
+	e := configs.PurgeEndpoint{URL: ts.URL, Headers: map[string]string{"X-Token": "secret"}}
+	targets := []Target{{Path: "/blog.html"}, {Path: "/news", Prefix: true}}
+	err := SendBan(s, e, targets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bans) != 2 {
+		t.Fatalf("sent %d bans, expected 2", len(bans))
+	}
+	tests := []struct {
+		ban   http.Header
+		url   string
+		match []string
+		miss  []string
+	}{
+		{bans[0], `^/blog\.html(\?.*)?$`, []string{"/blog.html", "/blog.html?page=2"}, []string{"/blogxhtml", "/blog.html/more"}},
+		{bans[1], `^/news`, []string{"/news", "/news/today"}, []string{"/old/news"}},
+	}
+	for _, test := range tests {
+		if test.ban.Get("X-Token") != "secret" {
+			t.Errorf("endpoint headers are not sent")
+		}
+		urlexp := test.ban.Get("X-Ban-Url")
+		if urlexp != test.url {
+			t.Errorf("url expression is %s, expected %s", urlexp, test.url)
+		}
+		re := regexp.MustCompile(urlexp)
+		for _, u := range test.match {
+			if !re.MatchString(u) {
+				t.Errorf("%s does not match %s", urlexp, u)
+			}
+		}
+		for _, u := range test.miss {
+			if re.MatchString(u) {
+				t.Errorf("%s matches %s", urlexp, u)
+			}
+		}
+	}
+	hostexp := bans[0].Get("X-Ban-Host")
+	if hostexp != `^(example\.com|www\.example\.com)(:[0-9]+)?$` {
+		t.Errorf("host expression is %s", hostexp)
+	}
+	re := regexp.MustCompile(hostexp)
+	for _, host := range []string{"example.com", "www.example.com:8080"} {
+		if !re.MatchString(host) {
+			t.Errorf("%s does not match %s", hostexp, host)
+		}
+	}
+	for _, host := range []string{"examplexcom", "other.example.com"} {
+		if re.MatchString(host) {
+			t.Errorf("%s matches %s", hostexp, host)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package purge
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/mongolar/mongolar/configs"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var client = &http.Client{Timeout: 10 * time.Second}
+
+// Send a PURGE request per target and site alias, prefixes can not be purged
+// this way so only their own url is.
+func SendPurge(s *configs.SiteConfig, e configs.PurgeEndpoint, targets []Target) error {
+	for _, t := range targets {
+		for _, host := range s.Aliases {
+			err := do(e, "PURGE", strings.TrimSuffix(e.URL, "/")+t.Path, host, nil, nil)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Send a BAN request per target with the url expression in X-Ban-Url and the
+// host expression in X-Ban-Host, for a Varnish vcl like
+//	ban("req.http.host ~ " + req.http.X-Ban-Host + " && req.url ~ " + req.http.X-Ban-Url);
+func SendBan(s *configs.SiteConfig, e configs.PurgeEndpoint, targets []Target) error {
+	hosts := make([]string, 0)
+	for _, host := range s.Aliases {
+		hosts = append(hosts, regexp.QuoteMeta(host))
+	}
+	hostexp := "^(" + strings.Join(hosts, "|") + ")(:[0-9]+)?$"
+	for _, t := range targets {
+		urlexp := "^" + regexp.QuoteMeta(t.Path)
+		if !t.Prefix {
+			urlexp += `(\?.*)?$`
+		}
+		headers := map[string]string{"X-Ban-Url": urlexp, "X-Ban-Host": hostexp}
+		err := do(e, "BAN", e.URL, "", headers, nil)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Post every target at once as json, for CDN apis and stand-ins.
+//	{"hosts": ["example.com"], "targets": [{"path": "/blog", "prefix": true}]}
+func SendHTTP(s *configs.SiteConfig, e configs.PurgeEndpoint, targets []Target) error {
+	body, err := json.Marshal(map[string]interface{}{"hosts": s.Aliases, "targets": targets})
+	if err != nil {
+		return err
+	}
+	headers := map[string]string{"Content-Type": "application/json"}
+	return do(e, "POST", e.URL, "", headers, body)
+}
+
+// Only log the targets, for development.
+func SendLog(s *configs.SiteConfig, e configs.PurgeEndpoint, targets []Target) error {
+	for _, t := range targets {
+		logmessage := fmt.Sprintf("Purge %s prefix %t", t.Path, t.Prefix)
+		s.Logger.Info(logmessage)
+	}
+	return nil
+}
+
+func do(e configs.PurgeEndpoint, method string, u string, host string, headers map[string]string, body []byte) error {
+	r, err := http.NewRequest(method, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if host != "" {
+		r.Host = host
+	}
+	for k, v := range e.Headers {
+		r.Header.Set(k, v)
+	}
+	for k, v := range headers {
+		r.Header.Set(k, v)
+	}
+	resp, err := client.Do(r)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	// Purging something that was never cached is not a failure.
+	if resp.StatusCode >= 400 && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("%s %s returned %s", method, u, resp.Status)
+	}
+	return nil
+}
@@ -1,6 +1,9 @@
 package jsconfig
 
 import (
+	"bytes"
+	"github.com/mongolar/mongolar/compress"
+	"github.com/mongolar/mongolar/configs"
 	"net/http"
 	"text/template"
 )
@@ -44,10 +47,12 @@ type JsConfigs struct {
 	AngularModules   []string
 }
 
-// Serve the config
-func (c *JsConfigs) Serve(w http.ResponseWriter) {
+// Serve the config, compressed when the site and client allow it.
+func (c *JsConfigs) Serve(w http.ResponseWriter, r *http.Request, s *configs.SiteConfig) {
 	t := template.New("Mongolar Config JS")
 	t.Parse(ConfigScript)
 	w.Header().Set("Content-Type", "application/javascript")
-	t.Execute(w, c)
+	var buf bytes.Buffer
+	t.Execute(&buf, c)
+	compress.Write(w, r, s, buf.Bytes())
 }
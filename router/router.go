@@ -2,14 +2,21 @@ package router
 
 import (
 	"fmt"
+	"github.com/mongolar/mongolar/compress"
 	"github.com/mongolar/mongolar/configs"
 	"github.com/mongolar/mongolar/controller"
+	"github.com/mongolar/mongolar/metrics"
+	"github.com/mongolar/mongolar/middleware"
+	"github.com/mongolar/mongolar/models/redirects"
 	"github.com/mongolar/mongolar/router/jsconfig"
 	"github.com/mongolar/mongolar/wrapper"
 	"net/http"
 	"os"
+	"path"
+	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 )
 
 // The Router should have everything needed to server multiple sites from one go instance
@@ -17,18 +24,27 @@ import (
 // Sites will have all the individual configurations with their key that relates to a Alias
 // APIEndPoint is a random string that generates each time a server boots and defines
 // where all API calls will take place.
+// Each site is served by its own handler wrapped in the site's HTTP middleware.
 type Router struct {
 	Aliases     configs.Aliases
 	Sites       configs.SitesMap
 	Controllers controller.ControllerMap
+	handlers    map[string]http.Handler
 }
 
 // The Constructor for the Router structure
+// Middleware is read from the site configurations here, so it must be
+// registered before the router is built.
 func New(a configs.Aliases, s configs.SitesMap, c controller.ControllerMap) *Router {
 	r := new(Router)
 	r.Aliases = a
 	r.Sites = s
 	r.Controllers = c
+	r.handlers = make(map[string]http.Handler)
+	for key, sc := range s {
+		h := &site{config: sc, controllers: c, middleware: middleware.Controllers(sc)}
+		r.handlers[key] = middleware.SiteHandler(sc, h)
+	}
 	return r
 }
 
@@ -37,76 +53,118 @@ func (ro Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	host := strings.Split(r.Host, ":")
 	// Does domain exist
 	if d, ok := ro.Aliases[host[0]]; ok {
+		ro.handlers[d].ServeHTTP(w, r)
+	} else {
+		// Domain was not found
+		wrapper.WriteError(w, r, 404, "Not Found") // Or Redirect?
+	}
+	return
 
-		pathvalues := strings.Split(r.URL.Path, "/")
-		pathvalues = pathvalues[1:]
-		// Set the the site config to an easy to use value.
-		s := ro.Sites[d]
-		logmessage := fmt.Sprintf("Request from accessed  %s : %s", r.URL.Path, r.RemoteAddr)
-		s.Logger.Info(logmessage)
-		switch pathvalues[0] {
-		// Mongolar config js is generated dynamically because it gets passed values from site config and endpoint is variable
-		// TODO move this to a controller
-		case "mongolar_config.js":
-			c := jsconfig.JsConfigs{
-				APIEndPoint:      s.APIEndPoint,
-				TemplateEndpoint: s.TemplateEndpoint,
-				ForeignDomains:   s.ForeignDomains,
-				AngularModules:   s.AngularModules,
-			}
-			c.Serve(w)
+}
 
-		// All static assets bypass AngularJS and get served as files.
-		// TODO Move this to a controller
-		case "assets":
-			d := s.Directory
-			f := d + r.URL.Path
-			info, err := os.Stat(f)
-			if err != nil {
-				if os.IsNotExist(err) {
-					http.NotFound(w, r)
-					return
-				}
-			}
-			if info.IsDir() {
+// The handler for one site
+type site struct {
+	config      *configs.SiteConfig
+	controllers controller.ControllerMap
+	middleware  []controller.Middleware
+}
+
+func (si *site) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	pathvalues := strings.Split(r.URL.Path, "/")
+	pathvalues = pathvalues[1:]
+	// Set the the site config to an easy to use value.
+	s := si.config
+	switch pathvalues[0] {
+	// Mongolar config js is generated dynamically because it gets passed values from site config and endpoint is variable
+	// TODO move this to a controller
+	case "mongolar_config.js":
+		c := jsconfig.JsConfigs{
+			APIEndPoint:      s.APIEndPoint,
+			TemplateEndpoint: s.TemplateEndpoint,
+			ForeignDomains:   s.ForeignDomains,
+			AngularModules:   s.AngularModules,
+		}
+		c.Serve(w, r, s)
+
+	// All static assets bypass AngularJS and get served as files.
+	// TODO Move this to a controller
+	case "assets":
+		// Only files under the assets directory are served.
+		clean := path.Clean("/" + r.URL.Path)
+		if !strings.HasPrefix(clean, "/assets/") {
+			http.NotFound(w, r)
+			return
+		}
+		f := filepath.Join(s.Directory, filepath.FromSlash(clean))
+		info, err := os.Stat(f)
+		if err != nil {
+			if os.IsNotExist(err) {
 				http.NotFound(w, r)
 				return
 			}
-			http.ServeFile(w, r, f)
+			errmessage := fmt.Sprintf("Unable to read asset %s : %s", f, err.Error())
+			wrapper.RequestLogger(s, w, r).Error(errmessage)
+			wrapper.WriteError(w, r, 500, "Internal Server Error")
+			return
+		}
+		if info.IsDir() {
+			http.NotFound(w, r)
+			return
+		}
+		compress.ServeFile(w, r, f)
 
-		// If path is ApiEndPoint this is an API request.
-		case s.APIEndPoint:
-			i := sort.SearchStrings(s.Controllers, pathvalues[1])
-			if s.Controllers[i] == pathvalues[1] {
-				w.Header().Set("Content-Type", "application/json")
-				// Build a wrapper for the controller
-				wr := wrapper.New(w, r, s)
-				wr.Shift()
-				//If the controller exists call it
-				if c, ok := ro.Controllers[wr.APIParams[0]]; ok {
-					wr.Shift()
-					c(wr)
-					return
-				} else {
-					http.Error(w, "Forbidden", 403)
-					return
+	// If path is ApiEndPoint this is an API request.
+	case s.APIEndPoint:
+		if len(pathvalues) < 2 {
+			wrapper.WriteError(w, r, 403, "Forbidden")
+			return
+		}
+		i := sort.SearchStrings(s.Controllers, pathvalues[1])
+		if i < len(s.Controllers) && s.Controllers[i] == pathvalues[1] {
+			w.Header().Set("Content-Type", "application/json")
+			// Build a wrapper for the controller
+			var wr *wrapper.Wrapper
+			if controller.Public[pathvalues[1]] {
+				wr = wrapper.NewPublic(w, r, s)
+			} else {
+				wr = wrapper.New(w, r, s)
+			}
+			wr.Shift()
+			//If the controller exists call it
+			name := wr.APIParams[0]
+			if c, ok := si.controllers[name]; ok {
+				if rec := wrapper.Recorder(w); rec != nil {
+					rec.Controller = name
 				}
+				wr.Shift()
+				start := time.Now()
+				controller.Chain(c, si.middleware...)(wr)
+				metrics.ControllerDuration.Since(start, s.Name, name)
+				return
 			} else {
-				http.Error(w, "Forbidden", 403)
+				wr.Error(403, "Forbidden")
 				return
 			}
-
-		// All other traffic will be handled by the AngularJs router
-		default:
-			d := s.Directory
-			http.ServeFile(w, r, d)
+		} else {
+			wrapper.WriteError(w, r, 403, "Forbidden")
 			return
 		}
 
-	} else {
-		// Domain was not found
-		http.Error(w, "Not Found", 404) // Or Redirect?
+	// All other traffic will be handled by the AngularJs router
+	// unless a redirect matches the url.
+	default:
+		dbs := s.DbSession.Copy()
+		rd, to, err := redirects.MatchDB(path.Clean(r.URL.Path), s, dbs.DB(""))
+		dbs.Close()
+		if err == nil {
+			if r.URL.RawQuery != "" && !strings.Contains(to, "?") {
+				to += "?" + r.URL.RawQuery
+			}
+			http.Redirect(w, r, to, rd.Status)
+			return
+		}
+		d := s.Directory
+		http.ServeFile(w, r, d)
+		return
 	}
-	return
-
 }
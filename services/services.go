@@ -38,3 +38,8 @@ type Dynamic struct {
 func SetDynamic(d Dynamic, w *wrapper.Wrapper) {
 	w.SetPayload("mongolar_dynamics", []Dynamic{d})
 }
+
+// Set more than one dynamic load, used when a controller changes several elements.
+func SetDynamics(d []Dynamic, w *wrapper.Wrapper) {
+	w.SetPayload("mongolar_dynamics", d)
+}
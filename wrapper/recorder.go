@@ -0,0 +1,39 @@
+package wrapper
+
+import (
+	"net/http"
+)
+
+// Records what the access log needs to know about a response.  The router
+// fills in the controller and New the user.
+type ResponseRecorder struct {
+	http.ResponseWriter
+	Status     int
+	Bytes      int
+	Controller string
+	UserId     string
+}
+
+// Record the first status code written.
+func (r *ResponseRecorder) WriteHeader(code int) {
+	if r.Status == 0 {
+		r.Status = code
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+// Count the bytes written, writing without a status code sends a 200.
+func (r *ResponseRecorder) Write(b []byte) (int, error) {
+	if r.Status == 0 {
+		r.Status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.Bytes += n
+	return n, err
+}
+
+// The recorder of a response, nil when it is not being recorded.
+func Recorder(w http.ResponseWriter) *ResponseRecorder {
+	rec, _ := w.(*ResponseRecorder)
+	return rec
+}
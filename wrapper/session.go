@@ -1,6 +1,8 @@
 package wrapper
 
 import (
+	"github.com/mongolar/mongolar/metrics"
+	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
 	"net"
 	"net/http"
@@ -11,6 +13,7 @@ import (
 type Session struct {
 	Id      bson.ObjectId `bson:"_id"`
 	Updated time.Time     `bson:"updated"`
+	UserId  bson.ObjectId `bson:"user_id,omitempty"`
 }
 
 //Session constructor
@@ -24,10 +27,11 @@ func (w *Wrapper) NewSession() error {
 	if err != nil && err.Error() != "http: named cookie not present" {
 		return err
 	}
-	//  If cookie is not set, set one
-	if c == nil {
+	//  If cookie is not set or is not one of ours, set one
+	if c == nil || !bson.IsObjectIdHex(c.Value) {
 		host, _, _ := net.SplitHostPort(w.Request.Host)
 		se.Id = bson.NewObjectId()
+		metrics.Sessions.Inc(w.SiteConfig.Name)
 		c = &http.Cookie{
 			Name:   "m_session_id",
 			Value:  se.Id.Hex(),
@@ -48,7 +52,15 @@ func (w *Wrapper) NewSession() error {
 func (w *Wrapper) SetSession() error {
 	w.Session.Updated = time.Now()
 	c := w.DbSession.DB("").C("sessions")
-	_, err := c.Upsert(bson.M{"_id": w.Session.Id}, bson.M{"$set": bson.M{"_id": w.Session.Id, "updated": w.Session.Updated}})
+	// Read the session back so the user is known without another query.
+	change := mgo.Change{
+		Update:    bson.M{"$set": bson.M{"_id": w.Session.Id, "updated": w.Session.Updated}},
+		Upsert:    true,
+		ReturnNew: true,
+	}
+	start := time.Now()
+	_, err := c.FindId(w.Session.Id).Apply(change, w.Session)
+	metrics.DBDuration.Since(start, w.SiteConfig.Name, "sessions")
 	if err != nil {
 		return err
 	}
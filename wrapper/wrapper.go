@@ -3,13 +3,19 @@
 package wrapper
 
 import (
+	"crypto/sha1"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/Sirupsen/logrus"
+	"github.com/mongolar/mongolar/compress"
 	"github.com/mongolar/mongolar/configs"
 	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"io"
 	"net/http"
 	"strings"
+	"time"
 )
 
 // Wrapper structure required to be passed back to the Controller
@@ -21,24 +27,53 @@ type Wrapper struct {
 	Payload    map[string]interface{} // This is the sum of the payload that will be returned to the user
 	DbSession  *mgo.Session           // The master MongoDb session that gets copied
 	APIParams  []string
+	RequestId  string        // Identifies the request in logs and error responses
+	Logger     *logrus.Entry // The site logger with the request id, site and user as fields
+}
+
+// The body of every error response
+type ErrorEnvelope struct {
+	Code      int    `json:"code"`
+	Message   string `json:"message"`
+	RequestId string `json:"request_id"`
 }
 
 //Constructor for the Wrapper
 func New(w http.ResponseWriter, r *http.Request, s *configs.SiteConfig) *Wrapper {
-	wr := Wrapper{Writer: w, Request: r, SiteConfig: s}
-	var err error
-	wr.DbSession = s.DbSession.Copy()
+	wr := newWrapper(w, r, s)
 	//Get session
-	err = wr.NewSession()
+	err := wr.NewSession()
 	if err != nil {
 		errmessage := fmt.Sprintf("Unable to create new session: %s", err.Error())
-		wr.SiteConfig.Logger.Error(errmessage)
+		wr.Logger.Error(errmessage)
 	}
 	err = wr.SetSession()
 	if err != nil {
 		errmessage := fmt.Sprintf("Unable to save session to db sessions: %s", err.Error())
-		wr.SiteConfig.Logger.Error(errmessage)
+		wr.Logger.Error(errmessage)
 	}
+	if wr.Session.UserId.Valid() {
+		wr.Logger = wr.Logger.WithField("user_id", wr.Session.UserId.Hex())
+		if rec := Recorder(w); rec != nil {
+			rec.UserId = wr.Session.UserId.Hex()
+		}
+	}
+	return wr
+}
+
+// Constructor for a Wrapper without a session, used for public controllers
+// whose responses are cached so requests without a cookie write nothing.
+func NewPublic(w http.ResponseWriter, r *http.Request, s *configs.SiteConfig) *Wrapper {
+	wr := newWrapper(w, r, s)
+	wr.Session = new(Session)
+	return wr
+}
+
+func newWrapper(w http.ResponseWriter, r *http.Request, s *configs.SiteConfig) *Wrapper {
+	wr := Wrapper{Writer: w, Request: r, SiteConfig: s}
+	wr.RequestId = RequestId(w, r)
+	wr.Logger = RequestLogger(s, w, r)
+	wr.DbSession = s.DbSession.Copy()
 	// Define payload
 	wr.Payload = make(map[string]interface{})
 	wr.APIParams = strings.Split(r.URL.Path, "/")
@@ -46,6 +81,73 @@ func New(w http.ResponseWriter, r *http.Request, s *configs.SiteConfig) *Wrapper
 	return &wr
 }
 
+// Constructor for a Wrapper outside of a web request, used by command line
+// tools and background jobs.  It has no request, writer or session.
+func NewBackground(s *configs.SiteConfig) *Wrapper {
+	wr := Wrapper{SiteConfig: s}
+	wr.Logger = s.Logger.WithField("site", s.Name)
+	wr.DbSession = s.DbSession.Copy()
+	wr.Payload = make(map[string]interface{})
+	wr.APIParams = make([]string, 0)
+	return &wr
+}
+
+// Close the Wrappers copy of the database session, Serve does this for web requests.
+func (w *Wrapper) Close() {
+	w.DbSession.Close()
+}
+
+// Get the id of a request, it is taken from the X-Request-Id header when a
+// proxy sent a usable one and generated otherwise.  The id is sent back in
+// the X-Request-Id response header.
+func RequestId(w http.ResponseWriter, r *http.Request) string {
+	if id := w.Header().Get("X-Request-Id"); id != "" {
+		return id
+	}
+	id := r.Header.Get("X-Request-Id")
+	if !validRequestId(id) {
+		id = bson.NewObjectId().Hex()
+	}
+	w.Header().Set("X-Request-Id", id)
+	return id
+}
+
+// The site logger with the request id and site as fields, for logging
+// where there is no Wrapper.
+func RequestLogger(s *configs.SiteConfig, w http.ResponseWriter, r *http.Request) *logrus.Entry {
+	return s.Logger.WithFields(logrus.Fields{"request_id": RequestId(w, r), "site": s.Name})
+}
+
+func validRequestId(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// Write an error response as a json ErrorEnvelope, used where there is no Wrapper.
+func WriteError(w http.ResponseWriter, r *http.Request, code int, message string) {
+	e := ErrorEnvelope{Code: code, Message: message, RequestId: RequestId(w, r)}
+	js, _ := json.Marshal(e)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Del("Content-Encoding")
+	w.WriteHeader(code)
+	w.Write(js)
+}
+
+// Serve an error instead of the payload.
+func (w *Wrapper) Error(code int, message string) {
+	WriteError(w.Writer, w.Request, code, message)
+	w.Close()
+	return
+}
+
 // Shift API Params over by one
 func (w *Wrapper) Shift() {
 	w.APIParams = w.APIParams[1:]
@@ -106,10 +208,87 @@ func (w *Wrapper) GetPayload() map[string]interface{} {
 func (w *Wrapper) Serve() {
 	js, err := json.Marshal(w.Payload)
 	if err != nil {
-		http.Error(w.Writer, err.Error(), http.StatusInternalServerError)
+		errmessage := fmt.Sprintf("Unable to marshal payload for %s : %s", w.Request.URL.Path, err.Error())
+		w.Logger.Error(errmessage)
+		w.Error(http.StatusInternalServerError, "Internal Server Error")
 		return
 	}
-	w.Writer.Write(js)
+	compress.Write(w.Writer, w.Request, w.SiteConfig, js)
+	w.DbSession.Close()
+	return
+}
+
+// Serve the payload with validators so browsers and proxies can cache it for
+// maxage seconds.  Conditional requests that still match are answered with
+// 304 Not Modified, a zero modtime sends no Last-Modified.
+func (w *Wrapper) ServeCached(modtime time.Time, maxage int) {
+	js, err := json.Marshal(w.Payload)
+	if err != nil {
+		errmessage := fmt.Sprintf("Unable to marshal payload for %s : %s", w.Request.URL.Path, err.Error())
+		w.Logger.Error(errmessage)
+		w.Error(http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	// The tag is weak because it is shared by the compressed and
+	// uncompressed bodies.
+	sum := sha1.Sum(js)
+	etag := fmt.Sprintf("\"%x\"", sum[:12])
+	h := w.Writer.Header()
+	// Shared caches must never store one visitor's session cookie.  Public
+	// controllers have no session, this covers any other controller.
+	h.Del("Set-Cookie")
+	h.Set("Cache-Control", fmt.Sprintf("public, max-age=%d", maxage))
+	h.Set("ETag", "W/"+etag)
+	if !modtime.IsZero() {
+		h.Set("Last-Modified", modtime.UTC().Format(http.TimeFormat))
+	}
+	if w.notModified(etag, modtime) {
+		w.Writer.WriteHeader(http.StatusNotModified)
+	} else {
+		compress.Write(w.Writer, w.Request, w.SiteConfig, js)
+	}
+	w.DbSession.Close()
+	return
+}
+
+// Check the request's If-None-Match, or If-Modified-Since without it.
+func (w *Wrapper) notModified(etag string, modtime time.Time) bool {
+	if w.Request.Method != "GET" && w.Request.Method != "HEAD" {
+		return false
+	}
+	if inm := w.Request.Header.Get("If-None-Match"); inm != "" {
+		for _, t := range strings.Split(inm, ",") {
+			t = strings.TrimPrefix(strings.TrimSpace(t), "W/")
+			if t == etag || t == "*" {
+				return true
+			}
+		}
+		return false
+	}
+	if modtime.IsZero() {
+		return false
+	}
+	ims, err := http.ParseTime(w.Request.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	return !modtime.Truncate(time.Second).After(ims)
+}
+
+// Serve a file download instead of the json payload.
+func (w *Wrapper) ServeAttachment(filename string, contenttype string, b []byte) {
+	w.Writer.Header().Set("Content-Type", contenttype)
+	w.Writer.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Writer.Write(b)
+	w.DbSession.Close()
+	return
+}
+
+// Serve a file inline instead of the json payload, conditional and range
+// requests are handled by http.ServeContent.
+func (w *Wrapper) ServeContent(name string, contenttype string, modtime time.Time, content io.ReadSeeker) {
+	w.Writer.Header().Set("Content-Type", contenttype)
+	http.ServeContent(w.Writer, w.Request, name, modtime, content)
 	w.DbSession.Close()
 	return
 }